@@ -0,0 +1,154 @@
+package dotprops
+
+import (
+	"fmt"
+	"log/slog"
+	"reflect"
+	"strings"
+)
+
+// Deprecation describes a value that was read from a deprecated key.
+type Deprecation struct {
+	// Key is the deprecated key the value was found under.
+	Key string
+	// Replacement is the key that should be used instead.
+	Replacement string
+	// Origin is where the value was defined.
+	Origin Origin
+}
+
+// WithDeprecationHandler sets the function called for every value read from a
+// key listed in a `deprecated=` tag option. Without it, deprecations are
+// logged as warnings to slog.Default().
+func WithDeprecationHandler(fn func(Deprecation)) DecoderOption {
+	return func(d *Decoder) {
+		d.deprecations = fn
+	}
+}
+
+// WithDeprecationLogger logs deprecations as warnings to logger.
+func WithDeprecationLogger(logger *slog.Logger) DecoderOption {
+	return WithDeprecationHandler(func(dep Deprecation) {
+		logDeprecation(logger, dep)
+	})
+}
+
+// logDeprecation logs dep as a warning, including the file and line of the
+// value when known.
+func logDeprecation(logger *slog.Logger, dep Deprecation) {
+	attrs := []interface{}{"key", dep.Key, "replacement", dep.Replacement, "source", dep.Origin.Source}
+	if dep.Origin.Line > 0 {
+		attrs = append(attrs, "line", dep.Origin.Line)
+	}
+	logger.Warn("deprecated property", attrs...)
+}
+
+// alias is an alternative key of a field, given with the alias= or
+// deprecated= option of its `property` tag. Both are relative to the prefix
+// of the field, like its own key.
+type alias struct {
+	key        string // full key of the field
+	old        string // full alternative key
+	deprecated bool
+}
+
+// fieldAliases collects the aliases of every field of a struct type.
+func fieldAliases(t reflect.Type) []alias {
+	var aliases []alias
+	collectAliases("", t, &aliases, make(map[reflect.Type]bool))
+	return aliases
+}
+
+// collectAliases walks the fields of t the same way collectDefaults does.
+func collectAliases(prefix string, t reflect.Type, aliases *[]alias, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		// Skip unexported fields and those tagged "-"
+		if !fieldType.IsExported() || skipped(fieldType) {
+			continue
+		}
+
+		ft := fieldType.Type
+		structType := ft
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+
+		// Embedded structs share the prefix of their parent
+		if fieldType.Anonymous {
+			if structType.Kind() == reflect.Struct {
+				collectAliases(prefix, structType, aliases, visiting)
+			}
+			continue
+		}
+
+		fullKey := joinKey(prefix, propertyKey(fieldType))
+
+		_, opts := parseTag(fieldType)
+		for _, old := range opts.values("alias") {
+			*aliases = append(*aliases, alias{key: fullKey, old: joinKey(prefix, old)})
+		}
+		for _, old := range opts.values("deprecated") {
+			*aliases = append(*aliases, alias{key: fullKey, old: joinKey(prefix, old), deprecated: true})
+		}
+
+		// Descend into nested structs unless the field decodes itself
+		if structType.Kind() == reflect.Struct && !decodesItself(structType) {
+			collectAliases(fullKey, structType, aliases, visiting)
+		}
+	}
+}
+
+// applyAliases copies the values found under the alternative keys of the
+// fields of t to their current keys, including every key below them. A value
+// under the current key that differs from the alternative one is an error,
+// unless it only comes from a `default` tag.
+func (d *Decoder) applyAliases(p *Properties, t reflect.Type) error {
+	for _, a := range fieldAliases(t) {
+		for _, key := range p.Keys() {
+			if key != a.old && !strings.HasPrefix(key, a.old+".") {
+				continue
+			}
+			target := a.key + strings.TrimPrefix(key, a.old)
+			e := p.entries[key]
+
+			if current, ok := p.entries[target]; ok && !current.fromTag {
+				if current.value != e.value {
+					kind := "alias"
+					if a.deprecated {
+						kind = "deprecated key"
+					}
+					return fmt.Errorf("property '%s' (from %s) conflicts with %s '%s' (from %s)", target, current.origin, kind, key, e.origin)
+				}
+			} else {
+				moved := *e
+				moved.shadowed = append([]Value(nil), e.shadowed...)
+				p.put(target, &moved)
+				if p.sensitive[key] {
+					p.sensitive[target] = true
+				}
+			}
+
+			if a.deprecated {
+				d.deprecated(Deprecation{Key: key, Replacement: target, Origin: e.origin})
+			}
+		}
+	}
+	return nil
+}
+
+// deprecated reports the use of a deprecated key.
+func (d *Decoder) deprecated(dep Deprecation) {
+	if d.deprecations != nil {
+		d.deprecations(dep)
+		return
+	}
+	logDeprecation(slog.Default(), dep)
+}
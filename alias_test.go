@@ -0,0 +1,157 @@
+package dotprops
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+)
+
+type RenamedConfig struct {
+	URL      string `property:"datasource.url,deprecated=db.url"`
+	Pool     int    `property:"datasource.pool,alias=db.pool" default:"5"`
+	Password string `property:"datasource.password,secret,deprecated=db.password"`
+	Cache    struct {
+		TTL int `property:"ttl"`
+	} `property:"cache,deprecated=caching"`
+}
+
+func TestUnmarshalDeprecatedKeys(t *testing.T) {
+	data := []byte(`db.url=jdbc:h2:mem
+db.pool=10
+caching.ttl=60
+`)
+
+	var deprecations []Deprecation
+	dec := NewDecoder(WithTagDefaults(), WithDeprecationHandler(func(dep Deprecation) {
+		deprecations = append(deprecations, dep)
+	}))
+
+	var config RenamedConfig
+	if err := dec.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.URL != "jdbc:h2:mem" {
+		t.Errorf("Expected URL 'jdbc:h2:mem', got '%s'", config.URL)
+	}
+	if config.Pool != 10 {
+		t.Errorf("Expected Pool 10, got %d", config.Pool)
+	}
+	if config.Cache.TTL != 60 {
+		t.Errorf("Expected Cache.TTL 60, got %d", config.Cache.TTL)
+	}
+
+	// Aliases are silent, deprecated keys are reported with their origin
+	if len(deprecations) != 2 {
+		t.Fatalf("Expected 2 deprecations, got %d: %+v", len(deprecations), deprecations)
+	}
+	expected := Deprecation{Key: "db.url", Replacement: "datasource.url", Origin: Origin{Line: 1}}
+	if deprecations[0] != expected {
+		t.Errorf("Expected %+v, got %+v", expected, deprecations[0])
+	}
+	if deprecations[1].Key != "caching.ttl" || deprecations[1].Replacement != "cache.ttl" || deprecations[1].Origin.Line != 3 {
+		t.Errorf("Expected caching.ttl from line 3, got %+v", deprecations[1])
+	}
+}
+
+func TestUnmarshalNewKeyTakesPrecedence(t *testing.T) {
+	data := []byte(`datasource.url=jdbc:h2:mem
+db.url=jdbc:h2:mem
+`)
+
+	var config RenamedConfig
+	dec := NewDecoder(WithTagDefaults(), WithDeprecationHandler(func(Deprecation) {}))
+	if err := dec.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.URL != "jdbc:h2:mem" || config.Pool != 5 {
+		t.Errorf("Expected URL 'jdbc:h2:mem' and Pool 5, got %+v", config)
+	}
+}
+
+func TestUnmarshalConflictingAlias(t *testing.T) {
+	data := []byte(`datasource.url=jdbc:h2:mem
+db.url=jdbc:h2:file
+`)
+
+	var config RenamedConfig
+	err := NewDecoder(WithDeprecationHandler(func(Deprecation) {})).Unmarshal(data, &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to conflicting keys, but it did not")
+	}
+	expected := "property 'datasource.url' (from <input>:1) conflicts with deprecated key 'db.url' (from <input>:2)"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err)
+	}
+}
+
+func TestAliasOverridesOnlyTagDefaults(t *testing.T) {
+	// A source named "defaults" is not a `default` tag
+	loader := NewLoader(
+		Map("defaults", map[string]string{"datasource.pool": "8"}),
+		Bytes("app.properties", []byte("db.pool=10\n")),
+	)
+
+	var config RenamedConfig
+	_, err := loader.Load(&config)
+	if err == nil || !strings.Contains(err.Error(), "property 'datasource.pool' (from defaults) conflicts with alias 'db.pool'") {
+		t.Errorf("Expected a conflict with the defaults source, got: %v", err)
+	}
+
+	// The tag default of datasource.pool gives way to the alias
+	loader = NewLoader(Bytes("app.properties", []byte("db.pool=10\n")))
+	if _, err := loader.Load(&config); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if config.Pool != 10 {
+		t.Errorf("Expected Pool 10, got %d", config.Pool)
+	}
+}
+
+func TestDecodeLeavesAliasedPropertiesAlone(t *testing.T) {
+	p, err := Parse([]byte("db.url=jdbc:x\ndb.password=secret\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	var config RenamedConfig
+	dec := NewDecoder(WithDeprecationHandler(func(Deprecation) {}))
+	if err := dec.Decode(p, &config); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if config.URL != "jdbc:x" || config.Password != "secret" {
+		t.Errorf("Expected URL jdbc:x and the password, got %+v", config)
+	}
+	if keys := p.Keys(); len(keys) != 2 || keys[0] != "db.url" || keys[1] != "db.password" {
+		t.Errorf("Expected keys [db.url db.password], got %v", keys)
+	}
+	if p.IsSensitive("db.password") || p.IsSensitive("datasource.password") {
+		t.Error("Expected Decode not to mark keys of the caller's set as sensitive")
+	}
+}
+
+func TestDeprecationLogger(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, nil))
+
+	loader := NewLoader(Bytes("app.properties", []byte("db.url=jdbc:h2:mem\ndb.password=hunter2\n")))
+	loader.Decoder = NewDecoder(WithDeprecationLogger(logger))
+
+	var config RenamedConfig
+	props, err := loader.Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	out := buf.String()
+	if !strings.Contains(out, "msg=\"deprecated property\" key=db.url replacement=datasource.url source=app.properties line=1") {
+		t.Errorf("Expected a warning for db.url, got:\n%s", out)
+	}
+	if strings.Contains(out, "hunter2") {
+		t.Errorf("Expected the warning not to contain the value, got:\n%s", out)
+	}
+	if !props.IsSensitive("datasource.password") || config.Password != "hunter2" {
+		t.Errorf("Expected the password to be decoded and marked sensitive")
+	}
+}
@@ -0,0 +1,133 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// DecodeFunc converts a property value to a value of the type it is
+// registered for with WithDecodeFunc.
+type DecodeFunc func(value string) (interface{}, error)
+
+// EncodeFunc converts a value of the type it is registered for with
+// WithEncodeFunc to a property value.
+type EncodeFunc func(v interface{}) (string, error)
+
+// DecodeHook converts a property value for a field of type target. A hook
+// that returns a value of the target type decides the result. A hook that
+// returns a string passes it on to the next hook, and finally to the
+// built-in decoding, so hooks can also rewrite values.
+type DecodeHook func(value string, target reflect.Type) (interface{}, error)
+
+// WithDecodeFunc registers fn to decode fields of type t, and pointers to t.
+// It is used instead of UnmarshalProp, UnmarshalText and the built-in
+// conversions, which makes it suitable for third-party types.
+func WithDecodeFunc(t reflect.Type, fn DecodeFunc) DecoderOption {
+	return func(d *Decoder) {
+		if d.decodeFuncs == nil {
+			d.decodeFuncs = make(map[reflect.Type]DecodeFunc)
+		}
+		d.decodeFuncs[t] = fn
+	}
+}
+
+// WithDecodeHook appends hooks to the chain consulted, in order, for every
+// value that has no DecodeFunc. Struct fields are only passed to hooks when
+// the property is a single value rather than a nested set of keys.
+func WithDecodeHook(hooks ...DecodeHook) DecoderOption {
+	return func(d *Decoder) {
+		d.hooks = append(d.hooks, hooks...)
+	}
+}
+
+// WithEncodeFunc registers fn to encode fields of type t, and pointers to t.
+// It is used instead of MarshalProp, MarshalText and the built-in
+// conversions.
+func WithEncodeFunc(t reflect.Type, fn EncodeFunc) EncoderOption {
+	return func(e *Encoder) {
+		if e.encodeFuncs == nil {
+			e.encodeFuncs = make(map[reflect.Type]EncodeFunc)
+		}
+		e.encodeFuncs[t] = fn
+	}
+}
+
+// decodeFunc returns the DecodeFunc registered for t or, if t is a pointer,
+// for the type it points to.
+func (d *decodeState) decodeFunc(t reflect.Type) (DecodeFunc, bool) {
+	if fn, ok := d.decodeFuncs[t]; ok {
+		return fn, true
+	}
+	if t.Kind() == reflect.Ptr {
+		fn, ok := d.decodeFuncs[t.Elem()]
+		return fn, ok
+	}
+	return nil, false
+}
+
+// decodesLeaf reports whether a single value for a struct or map field of
+// type t should be decoded as a leaf rather than rejected.
+func (d *decodeState) decodesLeaf(t reflect.Type) bool {
+	if _, ok := d.decodeFunc(t); ok || len(d.hooks) > 0 {
+		return true
+	}
+	ptr := reflect.PointerTo(indirectType(t))
+	if ptr.Implements(propUnmarshallerType) || ptr.Implements(textUnmarshalerType) {
+		return true
+	}
+	if d.fallback&BinaryFallback != 0 && ptr.Implements(binaryUnmarshalerType) {
+		return true
+	}
+	return d.fallback&JSONFallback != 0
+}
+
+// convert applies the DecodeFunc registered for the type of field, or else
+// the hook chain, to value. It reports whether field was set; if not, the
+// returned string is the value to decode in the usual way.
+func (d *decodeState) convert(field reflect.Value, value string) (bool, string, error) {
+	if fn, ok := d.decodeFunc(field.Type()); ok {
+		result, err := fn(value)
+		if err != nil {
+			return false, value, err
+		}
+		return true, value, assignConverted(field, result)
+	}
+
+	for _, hook := range d.hooks {
+		result, err := hook(value, field.Type())
+		if err != nil {
+			return false, value, err
+		}
+		if s, ok := result.(string); ok && field.Type() != reflect.TypeOf(s) {
+			value = s
+			continue
+		}
+		return true, value, assignConverted(field, result)
+	}
+	return false, value, nil
+}
+
+// assignConverted stores the result of a conversion in field, allocating it
+// if field is a pointer and result is of the type it points to.
+func assignConverted(field reflect.Value, result interface{}) error {
+	val := reflect.ValueOf(result)
+	switch {
+	case !val.IsValid():
+		field.Set(reflect.Zero(field.Type()))
+	case val.Type().AssignableTo(field.Type()):
+		field.Set(val)
+	case field.Kind() == reflect.Ptr && val.Type().AssignableTo(field.Type().Elem()):
+		ptr := reflect.New(field.Type().Elem())
+		ptr.Elem().Set(val)
+		field.Set(ptr)
+	default:
+		return fmt.Errorf("conversion returned %T, expected %s", result, field.Type())
+	}
+	return nil
+}
+
+// encodeFunc returns the EncodeFunc registered for t.
+func (e *encodeState) encodeFunc(t reflect.Type) (EncodeFunc, bool) {
+	fn, ok := e.encodeFuncs[t]
+	return fn, ok
+}
@@ -0,0 +1,151 @@
+package dotprops
+
+import (
+	"encoding/hex"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// ID and Money stand in for third-party types without UnmarshalText.
+type ID [4]byte
+
+type Money struct {
+	cents int64
+}
+
+type ConvertedConfig struct {
+	ID      ID            `property:"id"`
+	Parent  *ID           `property:"parent"`
+	Price   Money         `property:"price"`
+	Timeout time.Duration `property:"timeout"`
+	Name    string        `property:"name"`
+}
+
+func decodeID(value string) (interface{}, error) {
+	var id ID
+	b, err := hex.DecodeString(value)
+	if err != nil || len(b) != len(id) {
+		return nil, fmt.Errorf("invalid id %q", value)
+	}
+	copy(id[:], b)
+	return id, nil
+}
+
+func decodeMoney(value string) (interface{}, error) {
+	var units, cents int64
+	if _, err := fmt.Sscanf(value, "%d.%02d", &units, &cents); err != nil {
+		return nil, err
+	}
+	return Money{cents: units*100 + cents}, nil
+}
+
+// trimHook rewrites every value; durationHook decodes time.Duration fields.
+func trimHook(value string, target reflect.Type) (interface{}, error) {
+	return strings.TrimSpace(strings.Trim(value, `"`)), nil
+}
+
+func durationHook(value string, target reflect.Type) (interface{}, error) {
+	if target != reflect.TypeOf(time.Duration(0)) {
+		return value, nil
+	}
+	return time.ParseDuration(value)
+}
+
+func TestUnmarshalWithDecodeFuncsAndHooks(t *testing.T) {
+	data := []byte(`
+id=deadbeef
+parent=01020304
+price=12.50
+timeout="1m30s"
+name="service"
+`)
+
+	dec := NewDecoder(
+		WithDecodeFunc(reflect.TypeOf(ID{}), decodeID),
+		WithDecodeFunc(reflect.TypeOf(Money{}), decodeMoney),
+		WithDecodeHook(trimHook, durationHook),
+	)
+
+	var config ConvertedConfig
+	if err := dec.Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.ID != (ID{0xde, 0xad, 0xbe, 0xef}) {
+		t.Errorf("Expected ID deadbeef, got %x", config.ID)
+	}
+	if config.Parent == nil || *config.Parent != (ID{1, 2, 3, 4}) {
+		t.Errorf("Expected Parent 01020304, got %v", config.Parent)
+	}
+	if config.Price.cents != 1250 {
+		t.Errorf("Expected Price 1250 cents, got %d", config.Price.cents)
+	}
+	if config.Timeout != 90*time.Second {
+		t.Errorf("Expected Timeout 1m30s, got %v", config.Timeout)
+	}
+	if config.Name != "service" {
+		t.Errorf("Expected Name 'service', got '%s'", config.Name)
+	}
+}
+
+func TestUnmarshalDecodeFuncErrors(t *testing.T) {
+	dec := NewDecoder(WithDecodeFunc(reflect.TypeOf(ID{}), decodeID))
+
+	var config ConvertedConfig
+	err := dec.Unmarshal([]byte("id=xyz\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), `error converting field 'id' (from <input>:1): invalid id "xyz"`) {
+		t.Errorf("Expected a conversion error, got: %v", err)
+	}
+
+	dec = NewDecoder(WithDecodeFunc(reflect.TypeOf(ID{}), func(string) (interface{}, error) {
+		return "not an id", nil
+	}))
+	err = dec.Unmarshal([]byte("id=deadbeef\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "conversion returned string, expected dotprops.ID") {
+		t.Errorf("Expected a type mismatch error, got: %v", err)
+	}
+
+	// Without a converter a single value for a struct field is rejected
+	err = Unmarshal([]byte("price=12.50\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "expected map for nested struct field 'price'") {
+		t.Errorf("Expected a nested struct error, got: %v", err)
+	}
+}
+
+func TestMarshalWithEncodeFuncs(t *testing.T) {
+	parent := ID{1, 2, 3, 4}
+	config := &ConvertedConfig{
+		ID:      ID{0xde, 0xad, 0xbe, 0xef},
+		Parent:  &parent,
+		Price:   Money{cents: 1250},
+		Timeout: time.Second,
+		Name:    "service",
+	}
+
+	enc := NewEncoder(
+		WithEncodeFunc(reflect.TypeOf(ID{}), func(v interface{}) (string, error) {
+			id := v.(ID)
+			return hex.EncodeToString(id[:]), nil
+		}),
+		WithEncodeFunc(reflect.TypeOf(Money{}), func(v interface{}) (string, error) {
+			m := v.(Money)
+			return fmt.Sprintf("%d.%02d", m.cents/100, m.cents%100), nil
+		}),
+		WithEncodeFunc(reflect.TypeOf(time.Duration(0)), func(v interface{}) (string, error) {
+			return v.(time.Duration).String(), nil
+		}),
+	)
+
+	data, err := enc.Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := "id=deadbeef\nname=service\nparent=01020304\nprice=12.50\ntimeout=1s\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
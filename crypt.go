@@ -0,0 +1,199 @@
+package dotprops
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+)
+
+// Decrypter decrypts the contents of ENC(...) values.
+type Decrypter interface {
+	Decrypt(ciphertext string) (string, error)
+}
+
+// Encrypter encrypts values so that a matching Decrypter can recover them.
+type Encrypter interface {
+	Encrypt(plaintext string) (string, error)
+}
+
+// WithDecrypter decrypts values of the form ENC(ciphertext) with dec before
+// they are decoded. Without a decrypter, encrypted values are an error.
+func WithDecrypter(dec Decrypter) DecoderOption {
+	return func(d *Decoder) {
+		d.decrypter = dec
+	}
+}
+
+// EncryptKeys replaces the values of the given keys in p with ENC(...) values
+// produced by enc. Keys that are missing or already encrypted are left as is.
+func EncryptKeys(p *Properties, enc Encrypter, keys ...string) error {
+	for _, key := range keys {
+		e, ok := p.entries[key]
+		if !ok {
+			continue
+		}
+		if _, encrypted := encryptedValue(e.value); encrypted {
+			continue
+		}
+		ciphertext, err := enc.Encrypt(e.value)
+		if err != nil {
+			return fmt.Errorf("error encrypting key '%s': %v", key, err)
+		}
+		e.value = "ENC(" + ciphertext + ")"
+	}
+	return nil
+}
+
+// encryptedValue returns the ciphertext of an ENC(...) value.
+func encryptedValue(value string) (string, bool) {
+	value = strings.TrimSpace(value)
+	if strings.HasPrefix(value, "ENC(") && strings.HasSuffix(value, ")") {
+		return value[len("ENC(") : len(value)-1], true
+	}
+	return "", false
+}
+
+const (
+	aesSaltSize   = 16
+	aesKeySize    = 32
+	aesIterations = 210000
+)
+
+// AESGCM encrypts and decrypts values with AES-256-GCM using a key derived
+// from a passphrase with PBKDF2-HMAC-SHA256. Ciphertexts are the standard
+// base64 encoding of salt, nonce and sealed data. It is safe for concurrent
+// use.
+type AESGCM struct {
+	passphrase []byte
+
+	mu   sync.Mutex
+	salt []byte            // salt used for encryption, generated on first use
+	keys map[string][]byte // derived keys by salt
+}
+
+// NewAESGCM returns an AESGCM keyed from passphrase.
+func NewAESGCM(passphrase string) *AESGCM {
+	return &AESGCM{passphrase: []byte(passphrase), keys: make(map[string][]byte)}
+}
+
+// AESGCMFromEnv returns an AESGCM keyed from the passphrase in the
+// environment variable name.
+func AESGCMFromEnv(name string) (*AESGCM, error) {
+	passphrase, ok := os.LookupEnv(name)
+	if !ok || passphrase == "" {
+		return nil, fmt.Errorf("environment variable %s is not set", name)
+	}
+	return NewAESGCM(passphrase), nil
+}
+
+// Encrypt seals plaintext and returns the base64 encoded result.
+func (a *AESGCM) Encrypt(plaintext string) (string, error) {
+	a.mu.Lock()
+	if a.salt == nil {
+		salt := make([]byte, aesSaltSize)
+		if _, err := rand.Read(salt); err != nil {
+			a.mu.Unlock()
+			return "", err
+		}
+		a.salt = salt
+	}
+	salt := a.salt
+	a.mu.Unlock()
+
+	gcm, err := a.cipher(salt)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	out := append(append([]byte(nil), salt...), nonce...)
+	out = gcm.Seal(out, nonce, []byte(plaintext), nil)
+	return base64.StdEncoding.EncodeToString(out), nil
+}
+
+// Decrypt opens a ciphertext produced by Encrypt.
+func (a *AESGCM) Decrypt(ciphertext string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(strings.TrimSpace(ciphertext))
+	if err != nil {
+		return "", err
+	}
+	if len(data) < aesSaltSize {
+		return "", errors.New("ciphertext too short")
+	}
+
+	salt, data := data[:aesSaltSize], data[aesSaltSize:]
+	gcm, err := a.cipher(salt)
+	if err != nil {
+		return "", err
+	}
+	if len(data) < gcm.NonceSize() {
+		return "", errors.New("ciphertext too short")
+	}
+
+	nonce, data := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	plaintext, err := gcm.Open(nil, nonce, data, nil)
+	if err != nil {
+		return "", err
+	}
+	return string(plaintext), nil
+}
+
+// cipher returns the AEAD for the key derived with salt.
+func (a *AESGCM) cipher(salt []byte) (cipher.AEAD, error) {
+	a.mu.Lock()
+	key, ok := a.keys[string(salt)]
+	if !ok {
+		key = pbkdf2SHA256(a.passphrase, salt, aesIterations, aesKeySize)
+		a.keys[string(salt)] = key
+	}
+	a.mu.Unlock()
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// pbkdf2SHA256 derives a key from password and salt as described in RFC 8018.
+func pbkdf2SHA256(password, salt []byte, iterations, keyLen int) []byte {
+	prf := hmac.New(sha256.New, password)
+	hashLen := prf.Size()
+	numBlocks := (keyLen + hashLen - 1) / hashLen
+
+	var buf [4]byte
+	dk := make([]byte, 0, numBlocks*hashLen)
+	u := make([]byte, hashLen)
+	for block := 1; block <= numBlocks; block++ {
+		prf.Reset()
+		prf.Write(salt)
+		binary.BigEndian.PutUint32(buf[:], uint32(block))
+		prf.Write(buf[:])
+		dk = prf.Sum(dk)
+		t := dk[len(dk)-hashLen:]
+		copy(u, t)
+
+		for n := 2; n <= iterations; n++ {
+			prf.Reset()
+			prf.Write(u)
+			u = prf.Sum(u[:0])
+			for i := range u {
+				t[i] ^= u[i]
+			}
+		}
+	}
+	return dk[:keyLen]
+}
@@ -0,0 +1,107 @@
+package dotprops
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestPBKDF2SHA256(t *testing.T) {
+	tests := []struct {
+		iterations int
+		expected   string
+	}{
+		{1, "120fb6cffcf8b32c43e7225256c4f837a86548c92ccc35480805987cb70be17b"},
+		{2, "ae4d0c95af6b46d32d0adff928f06dd02a303f8ef3c251dfd6e2d85a95474c43"},
+	}
+
+	for _, tt := range tests {
+		key := pbkdf2SHA256([]byte("password"), []byte("salt"), tt.iterations, 32)
+		if got := hex.EncodeToString(key); got != tt.expected {
+			t.Errorf("pbkdf2SHA256 with %d iterations = %s, expected %s", tt.iterations, got, tt.expected)
+		}
+	}
+}
+
+func TestAESGCMRoundTrip(t *testing.T) {
+	aes := NewAESGCM("passphrase")
+
+	ciphertext, err := aes.Encrypt("hunter2")
+	if err != nil {
+		t.Fatalf("Encrypt failed: %v", err)
+	}
+	if strings.Contains(ciphertext, "hunter2") {
+		t.Fatalf("Expected ciphertext not to contain the plaintext, got %s", ciphertext)
+	}
+
+	plaintext, err := NewAESGCM("passphrase").Decrypt(ciphertext)
+	if err != nil {
+		t.Fatalf("Decrypt failed: %v", err)
+	}
+	if plaintext != "hunter2" {
+		t.Errorf("Expected plaintext 'hunter2', got '%s'", plaintext)
+	}
+
+	if _, err := NewAESGCM("wrong").Decrypt(ciphertext); err == nil {
+		t.Error("Expected Decrypt with the wrong passphrase to fail, but it did not")
+	}
+	if _, err := aes.Decrypt("AAAA"); err == nil {
+		t.Error("Expected Decrypt of a short ciphertext to fail, but it did not")
+	}
+}
+
+func TestAESGCMFromEnv(t *testing.T) {
+	if _, err := AESGCMFromEnv("DOTPROPS_UNSET_PASSPHRASE"); err == nil {
+		t.Error("Expected AESGCMFromEnv to fail for an unset variable, but it did not")
+	}
+
+	t.Setenv("DOTPROPS_PASSPHRASE", "passphrase")
+	if _, err := AESGCMFromEnv("DOTPROPS_PASSPHRASE"); err != nil {
+		t.Errorf("AESGCMFromEnv failed: %v", err)
+	}
+}
+
+func TestEncryptKeysAndDecode(t *testing.T) {
+	props, err := Parse([]byte(`
+database.host=localhost
+database.username=admin
+database.password=hunter2
+`))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	aes := NewAESGCM("passphrase")
+	if err := EncryptKeys(props, aes, "database.password", "missing.key"); err != nil {
+		t.Fatalf("EncryptKeys failed: %v", err)
+	}
+
+	encrypted, _ := props.Get("database.password")
+	if !strings.HasPrefix(encrypted, "ENC(") || strings.Contains(string(props.Bytes()), "hunter2") {
+		t.Fatalf("Expected database.password to be encrypted, got:\n%s", props.Bytes())
+	}
+
+	// Encrypting again leaves the value untouched
+	if err := EncryptKeys(props, aes, "database.password"); err != nil {
+		t.Fatalf("EncryptKeys failed: %v", err)
+	}
+	if again, _ := props.Get("database.password"); again != encrypted {
+		t.Errorf("Expected encrypted value to be left as is, got %s", again)
+	}
+
+	var config NestedConfig
+	if err := NewDecoder(WithDecrypter(aes)).Unmarshal(props.Bytes(), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Database.Password != "hunter2" {
+		t.Errorf("Expected Database.Password 'hunter2', got '%s'", config.Database.Password)
+	}
+
+	err = Unmarshal(props.Bytes(), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal without a decrypter to fail, but it did not")
+	}
+	if !strings.Contains(err.Error(), "no decrypter is configured") {
+		t.Errorf("Expected error to mention the missing decrypter, got: %v", err)
+	}
+}
@@ -0,0 +1,56 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// WithMaxDepth limits how deeply Marshal descends into nested structs. Fields
+// of the top-level struct are at depth 0, the fields of a struct nested in
+// one at depth 1, and so on; embedded structs do not count. Marshal fails on
+// a struct below depth n. The default, 0, sets no limit.
+func WithMaxDepth(n int) EncoderOption {
+	return func(e *Encoder) {
+		e.maxDepth = n
+	}
+}
+
+// visitKey identifies a pointer on the path being encoded. The type is part
+// of the key since a struct and its first field share an address.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// visit records that the pointer ptr is followed at fullKey. It fails if ptr
+// is already on the path to fullKey, which would make encoding recurse
+// forever. Call the returned function when done with ptr; pointers shared by
+// separate branches are not cycles.
+func (e *encodeState) visit(ptr reflect.Value, fullKey string) (func(), error) {
+	key := visitKey{ptr.Pointer(), ptr.Type()}
+	if first, ok := e.path[key]; ok {
+		target := "the root"
+		if first != "" {
+			target = "'" + first + "'"
+		}
+		return nil, fmt.Errorf("cycle detected at field '%s': %s points back to %s", fullKey, ptr.Type(), target)
+	}
+	e.path[key] = fullKey
+	return func() { delete(e.path, key) }, nil
+}
+
+// encodeNested encodes a nested struct below fullKey, within the maximum
+// depth. Every field below a sensitive field is sensitive too.
+func (e *encodeState) encodeNested(fullKey string, val reflect.Value, sensitive bool, props *Properties) error {
+	if e.maxDepth > 0 && e.depth >= e.maxDepth {
+		return fmt.Errorf("field '%s' exceeds the maximum depth of %d", fullKey, e.maxDepth)
+	}
+	e.depth++
+	secret := e.secret
+	e.secret = sensitive
+	defer func() {
+		e.depth--
+		e.secret = secret
+	}()
+	return e.encodeStruct(fullKey, val, props)
+}
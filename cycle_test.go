@@ -0,0 +1,100 @@
+package dotprops
+
+import (
+	"strings"
+	"testing"
+)
+
+type TreeNode struct {
+	Name     string    `property:"name"`
+	Parent   *TreeNode `property:"parent"`
+	Child    *TreeNode `property:"child"`
+	Internal string    `property:"-"`
+}
+
+func TestMarshalCycle(t *testing.T) {
+	root := &TreeNode{Name: "root"}
+	child := &TreeNode{Name: "child", Parent: root}
+	root.Child = child
+
+	_, err := Marshal(root)
+	if err == nil {
+		t.Fatal("Expected Marshal to fail due to a cycle, but it did not")
+	}
+	expected := "cycle detected at field 'child.parent': *dotprops.TreeNode points back to the root"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err)
+	}
+
+	// A cycle below the root names the key it first appeared at
+	type Holder struct {
+		Tree *TreeNode `property:"tree"`
+	}
+	_, err = Marshal(Holder{Tree: root})
+	if err == nil || !strings.Contains(err.Error(), "at field 'tree.child.parent'") || !strings.Contains(err.Error(), "back to 'tree'") {
+		t.Errorf("Expected a cycle error naming the key path, got: %v", err)
+	}
+}
+
+func TestMarshalSharedPointer(t *testing.T) {
+	shared := &TreeNode{Name: "shared"}
+	type Pair struct {
+		Left  *TreeNode `property:"left"`
+		Right *TreeNode `property:"right"`
+	}
+
+	data, err := Marshal(Pair{Left: shared, Right: shared})
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "left.name=shared\nright.name=shared\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestMarshalMaxDepth(t *testing.T) {
+	tree := &TreeNode{Name: "a", Child: &TreeNode{Name: "b", Child: &TreeNode{Name: "c"}}}
+
+	data, err := NewEncoder(WithMaxDepth(2)).Marshal(tree)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "child.child.name=c\nchild.name=b\nname=a\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	_, err = NewEncoder(WithMaxDepth(1)).Marshal(tree)
+	expectedErr := "field 'child.child' exceeds the maximum depth of 1"
+	if err == nil || err.Error() != expectedErr {
+		t.Errorf("Expected error %q, got %v", expectedErr, err)
+	}
+}
+
+func TestSkipTag(t *testing.T) {
+	type Config struct {
+		Name   string    `property:"name"`
+		Cache  *TreeNode `property:"-"`
+		Secret string    `property:"-" default:"unused"`
+		Dash   string    `property:"-,"`
+	}
+
+	config := Config{Name: "app", Cache: &TreeNode{Name: "x"}, Secret: "s", Dash: "d"}
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "-=d\nname=app\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded Config
+	if err := Unmarshal([]byte("name=app\nCache.name=x\nSecret=s\n-=d\n"), &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Cache != nil || decoded.Secret != "" || decoded.Dash != "d" || decoded.Name != "app" {
+		t.Errorf("Expected skipped fields to stay empty, got %+v", decoded)
+	}
+}
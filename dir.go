@@ -0,0 +1,113 @@
+package dotprops
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Duplicate describes a key that is defined by more than one fragment of a
+// DirSource.
+type Duplicate struct {
+	Key string
+	// Values holds every definition of the key in load order; the last one
+	// wins.
+	Values []Value
+}
+
+// DirSource reads a set of properties fragments, such as the files of a
+// conf.d directory, in lexical order of their paths. Later fragments take
+// precedence over earlier ones.
+type DirSource struct {
+	// OnDuplicate, if set, is called for every key defined by more than one
+	// fragment, in order of first definition. Returning an error aborts the
+	// load.
+	OnDuplicate func(d Duplicate) error
+
+	dir     string
+	pattern string
+	files   *fileReader
+}
+
+// Dir returns a source that reads every *.properties file in dir. dir must
+// exist; wrap the source in Optional to allow it to be missing.
+func Dir(dir string) *DirSource {
+	return &DirSource{dir: dir, pattern: filepath.Join(dir, "*.properties"), files: &fileReader{}}
+}
+
+// Glob returns a source that reads every file matching pattern, using the
+// syntax of filepath.Match.
+func Glob(pattern string) *DirSource {
+	return &DirSource{pattern: pattern, files: &fileReader{}}
+}
+
+// GlobFS returns a source that reads every file in fsys matching pattern,
+// using the syntax of path.Match.
+func GlobFS(fsys fs.FS, pattern string) *DirSource {
+	return &DirSource{pattern: pattern, files: &fileReader{fsys: fsys}}
+}
+
+// Name returns the directory or pattern the source reads.
+func (s *DirSource) Name() string {
+	if s.dir != "" {
+		return s.dir
+	}
+	return s.pattern
+}
+
+// Load reads and merges every matching fragment.
+func (s *DirSource) Load() (*Properties, error) {
+	return s.loadSyntax(PlainSyntax)
+}
+
+// loadSyntax is Load for fragments in the given syntax.
+func (s *DirSource) loadSyntax(syntax Syntax) (*Properties, error) {
+	files := s.files.withSyntax(syntax)
+	if s.dir != "" {
+		if _, err := os.Stat(s.dir); err != nil {
+			return nil, err
+		}
+	}
+
+	var names []string
+	var err error
+	if s.files.fsys != nil {
+		names, err = fs.Glob(s.files.fsys, s.pattern)
+	} else {
+		names, err = filepath.Glob(s.pattern)
+	}
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(names)
+
+	p := NewProperties()
+	var duplicates []string
+	definitions := make(map[string][]Value)
+	for _, name := range names {
+		fragment, err := files.load(name)
+		if err != nil {
+			return nil, err
+		}
+		for _, key := range fragment.keys {
+			e := fragment.entries[key]
+			if len(definitions[key]) == 1 {
+				duplicates = append(duplicates, key)
+			}
+			definitions[key] = append(definitions[key], Value{Text: e.value, Origin: e.origin})
+		}
+		p.Merge(fragment)
+	}
+
+	if s.OnDuplicate != nil {
+		for _, key := range duplicates {
+			if err := s.OnDuplicate(Duplicate{Key: key, Values: definitions[key]}); err != nil {
+				return nil, fmt.Errorf("duplicate key %s: %v", key, err)
+			}
+		}
+	}
+
+	return p, nil
+}
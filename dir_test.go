@@ -0,0 +1,83 @@
+package dotprops
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestDirLexicalOrder(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"10-base.properties":     "app.name=Base\napp.port=8080\n",
+		"20-override.properties": "app.port=9090\n",
+		"99-local.properties":    "app.debug=true\napp.port=9999\n",
+		"notes.txt":              "app.name=Ignored\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var duplicates []Duplicate
+	src := Dir(dir)
+	src.OnDuplicate = func(d Duplicate) error {
+		duplicates = append(duplicates, d)
+		return nil
+	}
+
+	var config SimpleConfig
+	if _, err := NewLoader(src).Load(&config); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.AppName != "Base" || config.Port != 9999 || config.Debug != true {
+		t.Errorf("Expected Base/9999/true, got %+v", config)
+	}
+
+	if len(duplicates) != 1 || duplicates[0].Key != "app.port" {
+		t.Fatalf("Expected one duplicate for app.port, got %+v", duplicates)
+	}
+	values := duplicates[0].Values
+	if len(values) != 3 || values[0].Text != "8080" || values[2].Text != "9999" {
+		t.Errorf("Expected definitions 8080, 9090, 9999, got %+v", values)
+	}
+	if !strings.HasSuffix(values[1].Origin.String(), "20-override.properties:1") {
+		t.Errorf("Expected second definition from 20-override.properties:1, got '%s'", values[1].Origin)
+	}
+}
+
+func TestDirDuplicateError(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf.d/a.properties": {Data: []byte("key=a\n")},
+		"conf.d/b.properties": {Data: []byte("key=b\n")},
+	}
+
+	src := GlobFS(fsys, "conf.d/*.properties")
+	src.OnDuplicate = func(d Duplicate) error {
+		return errors.New("not allowed")
+	}
+
+	_, err := src.Load()
+	if err == nil {
+		t.Fatal("Expected Load to fail on a duplicate key, but it did not")
+	}
+	if !strings.Contains(err.Error(), "duplicate key key") {
+		t.Errorf("Expected error to name the duplicate key, got: %v", err)
+	}
+}
+
+func TestDirMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "conf.d")
+
+	if _, err := Dir(missing).Load(); err == nil {
+		t.Fatal("Expected Load to fail for a missing directory, but it did not")
+	}
+	if _, err := Optional(Dir(missing)).Load(); err != nil {
+		t.Fatalf("Expected optional missing directory to load, got: %v", err)
+	}
+}
@@ -0,0 +1,443 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Document is a properties file kept line by line, so that it can be changed
+// and written back with its comments, blank lines, key order and formatting
+// intact. Use Encoder.Update to merge a struct into it:
+//
+//	doc, err := dotprops.ParseDocument(data)
+//	...
+//	err = dotprops.NewEncoder().Update(doc, &config)
+//	os.WriteFile(path, doc.Bytes(), 0o644)
+type Document struct {
+	// Decoder decodes the document in Update to find the fields that did
+	// not change. If nil, NewDecoder() is used.
+	Decoder *Decoder
+
+	lines  []docLine
+	ending string
+	final  bool
+	syntax Syntax
+}
+
+// docLine is a logical line of a Document: a property together with its
+// continuation lines, a comment or a blank line.
+type docLine struct {
+	raw []string // physical lines as written
+
+	// key and value are empty for comments and blank lines. indent and sep
+	// are the whitespace before the key and the separator with the
+	// whitespace around it, reused when the value is rewritten.
+	key, value  string
+	indent, sep string
+}
+
+// ParseDocument reads properties data in PlainSyntax into a Document.
+func ParseDocument(data []byte) (*Document, error) {
+	return PlainSyntax.ParseDocument(data)
+}
+
+// ParseDocument reads properties data in the syntax s into a Document. Values
+// written to the document are escaped for the same syntax.
+func (s Syntax) ParseDocument(data []byte) (*Document, error) {
+	text := string(data)
+	doc := &Document{ending: "\n", final: text == "" || strings.HasSuffix(text, "\n"), syntax: s}
+	if strings.Contains(text, "\r\n") {
+		doc.ending = "\r\n"
+	}
+	if text == "" {
+		return doc, nil
+	}
+
+	physical := strings.Split(strings.TrimSuffix(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), "\n")
+	for i := 0; i < len(physical); i++ {
+		start := i
+		var line string
+		line, i = s.joinLines(physical, i)
+
+		dl := docLine{raw: physical[start : i+1]}
+		if match := s.match(line); match != nil && !isComment(line) {
+			first := physical[start]
+			value := strings.TrimLeft(match[2], " \t")
+			dl.key = s.unescape(strings.TrimSpace(match[1]))
+			dl.value = s.unescape(strings.TrimSpace(match[2]))
+			dl.indent = first[:len(first)-len(strings.TrimLeft(first, " \t"))]
+			dl.sep = line[len(strings.TrimRight(match[1], " \t")) : len(line)-len(value)]
+		}
+		doc.lines = append(doc.lines, dl)
+	}
+	return doc, nil
+}
+
+// Bytes returns the document as text. Lines that were not changed are
+// written exactly as they were read.
+func (d *Document) Bytes() []byte {
+	ending := d.ending
+	if ending == "" {
+		ending = "\n"
+	}
+
+	var sb strings.Builder
+	n := 0
+	for _, line := range d.lines {
+		for _, raw := range line.raw {
+			if n > 0 {
+				sb.WriteString(ending)
+			}
+			sb.WriteString(raw)
+			n++
+		}
+	}
+	// Documents that were not parsed end in a line ending
+	if n > 0 && (d.final || d.ending == "") {
+		sb.WriteString(ending)
+	}
+	return []byte(sb.String())
+}
+
+// Properties parses the document into a property set, with the line numbers
+// of the document as origins.
+func (d *Document) Properties() (*Properties, error) {
+	return d.syntax.Parse(d.Bytes())
+}
+
+// base parses the base document into a property set.
+func (d *Document) base() (*Properties, error) {
+	docs, err := parseDocuments("", d.Bytes(), d.syntax, nil)
+	if err != nil {
+		return nil, err
+	}
+	return docs[0], nil
+}
+
+// Keys returns the keys of the document in the order of their first
+// occurrence.
+func (d *Document) Keys() []string {
+	var keys []string
+	seen := make(map[string]bool)
+	for _, line := range d.lines {
+		if line.key != "" && !seen[line.key] {
+			keys = append(keys, line.key)
+			seen[line.key] = true
+		}
+	}
+	return keys
+}
+
+// Get returns the value of key in the base document, the part before the first
+// "#---" or "!---" line. If the key occurs several times, the last occurrence
+// wins, as in Parse.
+func (d *Document) Get(key string) (string, bool) {
+	if i := d.find(key); i >= 0 {
+		return d.lines[i].value, true
+	}
+	return "", false
+}
+
+// Set sets the value of key in the base document. The line of an existing key
+// is rewritten in place, keeping its indentation and separator, unless the
+// value is the same. A new key is inserted after the last key sharing the
+// longest prefix of dot-separated segments with it, or else appended to the
+// base document as a new section after a blank line.
+func (d *Document) Set(key, value string) {
+	if i := d.find(key); i >= 0 {
+		if d.lines[i].value != value {
+			d.lines[i] = d.newLine(d.lines[i].indent, key, d.lines[i].sep, value)
+		}
+		return
+	}
+
+	end := d.baseEnd()
+	at, best := -1, 0
+	for i, line := range d.lines[:end] {
+		if line.key == "" {
+			continue
+		}
+		if n := commonSegments(line.key, key); n > 0 && n >= best {
+			at, best = i, n
+		}
+	}
+
+	if at < 0 {
+		// Keep the blank lines before a document separator after the new key
+		if end < len(d.lines) {
+			for end > 0 && d.lines[end-1].blank() {
+				end--
+			}
+		}
+		var lines []docLine
+		if end > 0 && !d.lines[end-1].blank() {
+			lines = append(lines, docLine{raw: []string{""}})
+		}
+		lines = append(lines, d.newLine("", key, d.separator(), value))
+		d.lines = append(d.lines[:end], append(lines, d.lines[end:]...)...)
+		return
+	}
+
+	line := d.newLine(d.lines[at].indent, key, d.lines[at].sep, value)
+	d.lines = append(d.lines[:at+1], append([]docLine{line}, d.lines[at+1:]...)...)
+}
+
+// Delete removes every occurrence of key. Comments above it are kept.
+func (d *Document) Delete(key string) {
+	lines := d.lines[:0]
+	for _, line := range d.lines {
+		if line.key != key {
+			lines = append(lines, line)
+		}
+	}
+	d.lines = lines
+}
+
+// find returns the index of the last line of key in the base document, or -1.
+func (d *Document) find(key string) int {
+	for i := d.baseEnd() - 1; i >= 0; i-- {
+		if d.lines[i].key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// baseEnd returns the index of the first document separator line, or the
+// number of lines if there is none.
+func (d *Document) baseEnd() int {
+	for i, line := range d.lines {
+		if line.key == "" && len(line.raw) == 1 {
+			if trimmed := strings.TrimSpace(line.raw[0]); trimmed == "#---" || trimmed == "!---" {
+				return i
+			}
+		}
+	}
+	return len(d.lines)
+}
+
+// separator returns the separator of the last property of the document, or
+// "=" if there is none.
+func (d *Document) separator() string {
+	for i := len(d.lines) - 1; i >= 0; i-- {
+		if d.lines[i].key != "" {
+			return d.lines[i].sep
+		}
+	}
+	return "="
+}
+
+// WithPruning makes Update remove the keys of the document that the struct no
+// longer encodes, including those of nil pointers. Keys referred to by
+// placeholders in the values that remain are kept, as are include directives
+// and the spring.config.activate.on-profile keys of profile documents.
+func WithPruning() EncoderOption {
+	return func(e *Encoder) {
+		e.prune = true
+	}
+}
+
+// Update merges the encoding of the struct v, or the struct v points to, into
+// the base document of doc. Keys whose values changed are rewritten in place,
+// and new keys are inserted next to related keys as described for
+// Document.Set. Values are never redacted.
+//
+// A field counts as changed if its encoding differs from that of the base
+// document decoded with doc.Decoder into a new value of the same type, with
+// the `default` tags applied as in Loader.Load. Placeholders, ENC(...) values
+// and the number formatting of unchanged fields are kept, and fields that
+// still have their default values are not added. Values the Decoder cannot
+// resolve, such as ENC(...) values without a decrypter or placeholders of
+// missing variables, are left as they are. Fields the document sets through
+// an alias or deprecated key are written to that key, or, WithPruning,
+// renamed to their current key instead of being removed.
+func (e *Encoder) Update(doc *Document, v interface{}) error {
+	val, err := structValue(v)
+	if err != nil {
+		return err
+	}
+
+	enc := *e
+	enc.redact = false
+	props, es, err := enc.encode(val)
+	if err != nil {
+		return err
+	}
+
+	// Encode the base document as it is for comparison
+	dec := doc.Decoder
+	if dec == nil {
+		dec = NewDecoder()
+	}
+	current, err := doc.base()
+	if err != nil {
+		return err
+	}
+	aliased := aliasedKeys(current, val.Type())
+	opaque := dec.opaqueKeys(current)
+	for key := range opaque {
+		current.Delete(key)
+	}
+	if !dec.overlay {
+		merged := tagDefaults(val.Type(), current)
+		merged.Merge(current)
+		current = merged
+	}
+	old := reflect.New(val.Type())
+	if err := dec.decode(current, old.Elem()); err != nil {
+		return fmt.Errorf("error decoding document: %v", err)
+	}
+	oldProps, _, err := enc.encode(old.Elem())
+	if err != nil {
+		return err
+	}
+
+	for _, key := range e.sortKeys(props.Keys()) {
+		if es.commented[key] || opaque[key] || opaque[aliased[key]] {
+			continue
+		}
+		value, _ := props.Get(key)
+		if prev, ok := oldProps.Get(key); ok && prev == value {
+			continue
+		}
+		// Keep writing values where the document defines them, unless
+		// pruning removes the alternative keys anyway
+		if old, ok := aliased[key]; ok && !e.prune {
+			key = old
+		}
+		doc.Set(key, value)
+	}
+
+	if e.prune {
+		keep := func(key string) bool {
+			_, ok := props.Get(key)
+			return ok && !es.commented[key]
+		}
+		// Move the values of alternative keys that pruning would remove
+		// to the keys of their fields
+		for key, old := range aliased {
+			if _, ok := doc.Get(key); !ok && keep(key) {
+				doc.rename(old, key)
+			}
+		}
+		return doc.prune(keep)
+	}
+	return nil
+}
+
+// opaqueKeys returns the keys of p whose values d cannot decode as text:
+// those with placeholders it fails to resolve and, without a decrypter,
+// ENC(...) values.
+func (d *Decoder) opaqueKeys(p *Properties) map[string]bool {
+	opaque := make(map[string]bool)
+	var in *interpolator
+	if d.interpolate {
+		in = newInterpolator(p, d.envLookup, d.lookups)
+	}
+	for _, key := range p.Keys() {
+		value, _ := p.Get(key)
+		if in != nil {
+			resolved, err := in.resolve(key, value)
+			if err != nil {
+				opaque[key] = true
+				continue
+			}
+			value = resolved
+		}
+		if _, encrypted := encryptedValue(value); encrypted && d.decrypter == nil {
+			opaque[key] = true
+		}
+	}
+	return opaque
+}
+
+// aliasedKeys maps the keys of the fields of t that p only sets through an
+// alias or deprecated key to that key.
+func aliasedKeys(p *Properties, t reflect.Type) map[string]string {
+	aliased := make(map[string]string)
+	keys := p.Keys()
+	for _, a := range fieldAliases(t) {
+		for _, key := range keys {
+			if key != a.old && !strings.HasPrefix(key, a.old+".") {
+				continue
+			}
+			target := a.key + strings.TrimPrefix(key, a.old)
+			if _, ok := p.Get(target); !ok {
+				aliased[target] = key
+			}
+		}
+	}
+	return aliased
+}
+
+// rename changes the key of the last line of old to key, keeping its value
+// and formatting.
+func (d *Document) rename(old, key string) {
+	if i := d.find(old); i >= 0 {
+		line := d.lines[i]
+		d.lines[i] = d.newLine(line.indent, key, line.sep, line.value)
+	}
+}
+
+// prune removes the keys for which keep returns false, unless a placeholder
+// in the value of a kept key refers to them.
+func (d *Document) prune(keep func(key string) bool) error {
+	p, err := d.Properties()
+	if err != nil {
+		return err
+	}
+
+	// Resolving the kept values caches every key they refer to
+	in := newInterpolator(p, false, nil)
+	for _, key := range p.Keys() {
+		if keep(key) {
+			// Unresolved placeholders refer to no key to keep
+			value, _ := p.Get(key)
+			_, _ = in.resolve(key, value)
+		}
+	}
+
+	for _, key := range d.Keys() {
+		if directive(key) {
+			continue
+		}
+		if _, referenced := in.resolved[key]; !keep(key) && !referenced {
+			d.Delete(key)
+		}
+	}
+	return nil
+}
+
+// directive reports whether key is an include directive or the key that
+// restricts a document to profiles.
+func directive(key string) bool {
+	return key == "include" || key == "includeoptional" || key == profileKey
+}
+
+// blank reports whether the line is empty.
+func (l docLine) blank() bool {
+	return len(l.raw) == 1 && strings.TrimSpace(l.raw[0]) == ""
+}
+
+// newLine returns a property line written with the given formatting.
+func (d *Document) newLine(indent, key, sep, value string) docLine {
+	return docLine{
+		raw:    []string{indent + d.syntax.escapeKey(key) + sep + d.syntax.escapeValue(value)},
+		key:    key,
+		value:  value,
+		indent: indent,
+		sep:    sep,
+	}
+}
+
+// commonSegments returns the number of leading dot-separated segments that a
+// and b share.
+func commonSegments(a, b string) int {
+	as, bs := strings.Split(a, "."), strings.Split(b, ".")
+	n := 0
+	for n < len(as) && n < len(bs) && as[n] == bs[n] {
+		n++
+	}
+	return n
+}
@@ -0,0 +1,298 @@
+package dotprops
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestDocumentRoundTrip(t *testing.T) {
+	data := "# App settings\r\n  app.name : demo\r\n\r\n! note\r\npath=C:\\\\dir\\\r\n    \\\\sub\r\nempty="
+
+	doc, err := JavaSyntax.ParseDocument([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	if got := string(doc.Bytes()); got != data {
+		t.Errorf("Expected the document unchanged:\n%q\nGot:\n%q", data, got)
+	}
+
+	if value, ok := doc.Get("app.name"); !ok || value != "demo" {
+		t.Errorf("Expected app.name to be demo, got %q", value)
+	}
+	if value, _ := doc.Get("path"); value != `C:\dir\sub` {
+		t.Errorf("Expected the continued value to be joined, got %q", value)
+	}
+	keys := doc.Keys()
+	if len(keys) != 3 || keys[0] != "app.name" || keys[1] != "path" || keys[2] != "empty" {
+		t.Errorf("Expected keys [app.name path empty], got %v", keys)
+	}
+}
+
+func TestDocumentSetAndDelete(t *testing.T) {
+	doc, err := ParseDocument([]byte(`# Database
+db.host = localhost
+db.port = 5432
+
+# Server
+server.port=8080
+`))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	doc.Set("db.port", "6543")
+	doc.Set("db.user", "admin")
+	doc.Set("server.port", "8080")
+	doc.Set("log.level", "info")
+	doc.Delete("db.host")
+
+	expected := `# Database
+db.port = 6543
+db.user = admin
+
+# Server
+server.port=8080
+
+log.level=info
+`
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+type UpdateConfig struct {
+	Name    string  `property:"app.name"`
+	URL     string  `property:"app.url"`
+	Ratio   float64 `property:"app.ratio"`
+	Retries int     `property:"app.retries" default:"3"`
+	DB      struct {
+		Host string `property:"host"`
+		Port int    `property:"port"`
+	} `property:"db"`
+	Cache *struct {
+		Size int `property:"size"`
+	} `property:"cache"`
+}
+
+const updateDocument = `# Application
+app.name = demo
+app.url  = http://${host}:80
+app.ratio = 0.50
+
+# Database
+db.host = localhost
+
+host=example.com
+cache.size=10
+`
+
+func TestEncoderUpdate(t *testing.T) {
+	doc, err := ParseDocument([]byte(updateDocument))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	var config UpdateConfig
+	if err := NewDecoder(WithTagDefaults()).Unmarshal(doc.Bytes(), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	config.Name = "renamed"
+	config.DB.Port = 5432
+
+	if err := NewEncoder(WithRedaction()).Update(doc, &config); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	expected := `# Application
+app.name = renamed
+app.url  = http://${host}:80
+app.ratio = 0.50
+
+# Database
+db.host = localhost
+db.port = 5432
+
+host=example.com
+cache.size=10
+`
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestEncoderUpdateWithPruning(t *testing.T) {
+	doc, err := ParseDocument([]byte(updateDocument))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	var config UpdateConfig
+	config.Name = "demo"
+	config.URL = "http://example.com:80"
+	config.Ratio = 0.5
+	config.Retries = 3
+	config.DB.Host = "localhost"
+
+	// db.port keeps the value the document decodes to, so it is not added;
+	// host is kept for the placeholder in app.url, which resolves to the
+	// value of the field
+	doc.Decoder = NewDecoder(WithInterpolation(true))
+	if err := NewEncoder(WithPruning()).Update(doc, config); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+
+	expected := `# Application
+app.name = demo
+app.url  = http://${host}:80
+app.ratio = 0.50
+
+# Database
+db.host = localhost
+
+host=example.com
+`
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
+
+func TestEncoderUpdateDecodeError(t *testing.T) {
+	doc, err := ParseDocument([]byte("db.port=abc\n"))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	var config UpdateConfig
+	err = NewEncoder().Update(doc, &config)
+	if err == nil {
+		t.Fatal("Expected Update to fail on an invalid document, but it did not")
+	}
+}
+
+func TestEncoderUpdateDocuments(t *testing.T) {
+	type Config struct {
+		Host string `property:"host"`
+		Port int    `property:"port"`
+		Name string `property:"name"`
+	}
+	doc, err := ParseDocument([]byte(`include=common.properties
+host=base
+port=1
+
+#---
+spring.config.activate.on-profile=dev
+host=dev
+`))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+
+	// Changes go to the base document; directives and profile keys stay
+	config := Config{Host: "changed", Port: 1, Name: "demo"}
+	if err := NewEncoder(WithPruning()).Update(doc, &config); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	expected := `include=common.properties
+host=changed
+port=1
+
+name=demo
+
+#---
+spring.config.activate.on-profile=dev
+host=dev
+`
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+	if value, _ := doc.Get("host"); value != "changed" {
+		t.Errorf("Expected host in the base document to be changed, got %q", value)
+	}
+}
+
+func TestEncoderUpdateOpaqueValues(t *testing.T) {
+	type Config struct {
+		Password string `property:"db.password"`
+		Home     string `property:"app.home"`
+		Name     string `property:"app.name"`
+	}
+	const data = `db.password=ENC(c2VjcmV0)
+app.home=${env:DOTPROPS_TEST_UNSET_HOME}
+app.name=demo
+`
+
+	// The default Decoder reads placeholders as written
+	tests := []struct {
+		name string
+		dec  *Decoder
+		home string
+	}{
+		{"default", nil, "${env:DOTPROPS_TEST_UNSET_HOME}"},
+		{"interpolating", NewDecoder(WithInterpolation(true)), "/home/app"},
+	}
+	for _, tt := range tests {
+		doc, err := ParseDocument([]byte(data))
+		if err != nil {
+			t.Fatalf("ParseDocument failed: %v", err)
+		}
+		doc.Decoder = tt.dec
+
+		// Values that cannot be decrypted or resolved are kept as written
+		config := Config{Password: "secret", Home: tt.home, Name: "renamed"}
+		if err := NewEncoder().Update(doc, &config); err != nil {
+			t.Fatalf("%s: Update failed: %v", tt.name, err)
+		}
+		expected := strings.Replace(data, "app.name=demo", "app.name=renamed", 1)
+		if got := string(doc.Bytes()); got != expected {
+			t.Errorf("%s: Expected:\n%s\nGot:\n%s", tt.name, expected, got)
+		}
+	}
+}
+
+func TestEncoderUpdateAliasedKeys(t *testing.T) {
+	const data = `# Database
+db.url = jdbc:${db.host}
+db.host = localhost
+caching.ttl=60
+`
+	silent := NewDecoder(WithInterpolation(true), WithTagDefaults(), WithDeprecationHandler(func(Deprecation) {}))
+
+	doc, err := ParseDocument([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	doc.Decoder = silent
+
+	var config RenamedConfig
+	if err := silent.Unmarshal(doc.Bytes(), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	// Pruning moves the values of deprecated keys to the current keys
+	if err := NewEncoder(WithPruning()).Update(doc, &config); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	expected := `# Database
+datasource.url = jdbc:${db.host}
+db.host = localhost
+cache.ttl=60
+`
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+
+	// Without pruning, changed values are written to the deprecated keys
+	doc, err = ParseDocument([]byte(data))
+	if err != nil {
+		t.Fatalf("ParseDocument failed: %v", err)
+	}
+	doc.Decoder = silent
+	config.Cache.TTL = 30
+	if err := NewEncoder().Update(doc, &config); err != nil {
+		t.Fatalf("Update failed: %v", err)
+	}
+	expected = strings.Replace(data, "caching.ttl=60", "caching.ttl=30", 1)
+	if got := string(doc.Bytes()); got != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, got)
+	}
+}
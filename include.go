@@ -0,0 +1,102 @@
+package dotprops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fileReader reads properties files from the OS or an fs.FS and resolves
+// include directives relative to the including file.
+type fileReader struct {
+	// fsys is the file system to read from; nil reads from the OS.
+	fsys fs.FS
+	// profiles are the active profiles used to select documents when
+	// evaluateProfiles is set. Otherwise every document is merged.
+	profiles         []string
+	evaluateProfiles bool
+	// syntax is the syntax of the files.
+	syntax Syntax
+}
+
+// withSyntax returns a copy of r that reads files in the given syntax.
+func (r *fileReader) withSyntax(syntax Syntax) *fileReader {
+	files := *r
+	files.syntax = syntax
+	return &files
+}
+
+// load reads the named file and everything it includes.
+func (r *fileReader) load(name string) (*Properties, error) {
+	data, err := r.readFile(name)
+	if err != nil {
+		return nil, err
+	}
+	return r.parse(name, data, nil)
+}
+
+// parse reads the contents of the named file. chain lists the files that
+// include it, outermost first, and is used to detect include cycles.
+func (r *fileReader) parse(name string, data []byte, chain []string) (*Properties, error) {
+	chain = append(chain[:len(chain):len(chain)], name)
+
+	include := func(target string, optional bool) (*Properties, error) {
+		target = r.resolve(name, target)
+		for _, prev := range chain {
+			if prev == target {
+				return nil, fmt.Errorf("include cycle: %s -> %s", strings.Join(chain, " -> "), target)
+			}
+		}
+
+		data, err := r.readFile(target)
+		if optional && errors.Is(err, fs.ErrNotExist) {
+			return NewProperties(), nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		return r.parse(target, data, chain)
+	}
+
+	docs, err := parseDocuments(name, data, r.syntax, include)
+	if err != nil {
+		return nil, err
+	}
+
+	if r.evaluateProfiles {
+		return mergeDocuments(docs, r.profiles)
+	}
+	p := NewProperties()
+	for _, doc := range docs {
+		p.Merge(doc)
+	}
+	return p, nil
+}
+
+// readFile reads the named file from the configured file system.
+func (r *fileReader) readFile(name string) ([]byte, error) {
+	if r.fsys == nil {
+		return os.ReadFile(name)
+	}
+	return fs.ReadFile(r.fsys, name)
+}
+
+// resolve returns the path of an included file relative to the directory of
+// the including file. Absolute paths are used as is on the OS and relative to
+// the root of an fs.FS.
+func (r *fileReader) resolve(from, name string) string {
+	if r.fsys == nil {
+		if filepath.IsAbs(name) {
+			return filepath.Clean(name)
+		}
+		return filepath.Join(filepath.Dir(from), name)
+	}
+	if strings.HasPrefix(name, "/") {
+		return path.Clean(strings.TrimPrefix(name, "/"))
+	}
+	return path.Join(path.Dir(from), name)
+}
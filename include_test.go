@@ -0,0 +1,117 @@
+package dotprops
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestIncludeRelativeToFile(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "shared"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	files := map[string]string{
+		"service.properties":       "app.name=Service\ninclude = shared/common.properties\napp.debug=true\n",
+		"shared/common.properties": "app.name=Common\napp.port=8080\napp.debug=false\n",
+	}
+	for name, data := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(data), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var config SimpleConfig
+	props, err := NewLoader(File(filepath.Join(dir, "service.properties"))).Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	// The include is merged in place: it overrides earlier keys and is
+	// overridden by later ones
+	if config.AppName != "Common" {
+		t.Errorf("Expected AppName 'Common', got '%s'", config.AppName)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Expected Port 8080, got %d", config.Port)
+	}
+	if config.Debug != true {
+		t.Errorf("Expected Debug true, got %v", config.Debug)
+	}
+	if _, ok := props.Get("include"); ok {
+		t.Error("Expected include directive not to be stored as a property")
+	}
+
+	origin, _ := props.Origin("app.port")
+	if origin.String() != filepath.Join(dir, "shared", "common.properties")+":2" {
+		t.Errorf("Expected origin in common.properties, got '%s'", origin)
+	}
+}
+
+func TestIncludeFS(t *testing.T) {
+	fsys := fstest.MapFS{
+		"conf/app.properties":    {Data: []byte("include=db.properties, /base.properties\nincludeoptional=missing.properties\n")},
+		"conf/db.properties":     {Data: []byte("database.host=db.local\n")},
+		"base.properties":        {Data: []byte("app.name=FromRoot\n")},
+		"conf/unused.properties": {Data: []byte("app.name=Unused\n")},
+	}
+
+	props, err := FS(fsys, "conf/app.properties").Load()
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if value, _ := props.Get("database.host"); value != "db.local" {
+		t.Errorf("Expected database.host 'db.local', got '%s'", value)
+	}
+	if value, _ := props.Get("app.name"); value != "FromRoot" {
+		t.Errorf("Expected app.name 'FromRoot', got '%s'", value)
+	}
+}
+
+func TestIncludeMissingShowsChain(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.properties": {Data: []byte("key=a\ninclude=b.properties\n")},
+		"b.properties": {Data: []byte("include=c.properties\n")},
+	}
+
+	_, err := Optional(FS(fsys, "a.properties")).Load()
+	if err == nil {
+		t.Fatal("Expected Load to fail for a missing include, but it did not")
+	}
+	if errors.Is(err, fs.ErrNotExist) {
+		t.Error("Expected a missing nested include not to look like a missing file")
+	}
+	if !strings.Contains(err.Error(), "a.properties:2: include b.properties: b.properties:1: include c.properties") {
+		t.Errorf("Expected error to show the include chain, got: %v", err)
+	}
+}
+
+func TestIncludeCycle(t *testing.T) {
+	fsys := fstest.MapFS{
+		"a.properties": {Data: []byte("include=b.properties\n")},
+		"b.properties": {Data: []byte("include=a.properties\n")},
+	}
+
+	_, err := FS(fsys, "a.properties").Load()
+	if err == nil {
+		t.Fatal("Expected Load to fail for an include cycle, but it did not")
+	}
+	if !strings.Contains(err.Error(), "include cycle: a.properties -> b.properties -> a.properties") {
+		t.Errorf("Expected error to describe the cycle, got: %v", err)
+	}
+}
+
+func TestIncludeIgnoredByParse(t *testing.T) {
+	props, err := Parse([]byte("include=other.properties\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if value, _ := props.Get("include"); value != "other.properties" {
+		t.Errorf("Expected include to be a plain key, got '%s'", value)
+	}
+}
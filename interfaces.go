@@ -1,14 +1,14 @@
 package dotprops
 
-// TextMarshaler interface as defined in the encoding package
-type TextMarshaler interface {
-	MarshalText() (text []byte, err error)
-}
+import "encoding"
 
-// TextUnmarshaler interface as defined in the encoding package
-type TextUnmarshaler interface {
-	UnmarshalText(text []byte) error
-}
+// TextMarshaler is encoding.TextMarshaler. Values implementing it are written
+// as a single property value.
+type TextMarshaler = encoding.TextMarshaler
+
+// TextUnmarshaler is encoding.TextUnmarshaler. Values implementing it are read
+// from a single property value.
+type TextUnmarshaler = encoding.TextUnmarshaler
 
 // PropMarshaler allows custom marshaling of a single property.
 type PropMarshaler interface {
@@ -19,3 +19,18 @@ type PropMarshaler interface {
 type PropUnmarshaller interface {
 	UnmarshalProp(key string, value string) error
 }
+
+// PropsMarshaler allows custom marshaling of a value as several properties.
+// MarshalProps receives the full key of the field and returns the properties
+// to write below it, keyed relative to that key.
+type PropsMarshaler interface {
+	MarshalProps(prefix string) (map[string]string, error)
+}
+
+// PropsUnmarshaller allows custom unmarshaling of a value from several
+// properties. UnmarshalProps receives the full key of the field and every
+// property below it, keyed relative to that key, with placeholders and
+// encrypted values resolved.
+type PropsUnmarshaller interface {
+	UnmarshalProps(prefix string, props map[string]string) error
+}
@@ -0,0 +1,186 @@
+package dotprops
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// interpolator resolves ${key} and ${key:default} placeholders against a
+// property set, and ${namespace:argument} placeholders through registered
+// lookups. "$${" produces a literal "${".
+type interpolator struct {
+	props     *Properties
+	envLookup bool
+	lookups   map[string]Lookup
+	// prefix is prepended to the keys passed to resolve, when decoding a
+	// sub-tree of props with keys relative to it.
+	prefix string
+	// resolved caches fully expanded values by key.
+	resolved map[string]string
+}
+
+// newInterpolator returns an interpolator for p. If envLookup is set, keys
+// missing from p are looked up in the environment.
+func newInterpolator(p *Properties, envLookup bool, lookups map[string]Lookup) *interpolator {
+	return &interpolator{props: p, envLookup: envLookup, lookups: lookups, resolved: make(map[string]string)}
+}
+
+// resolve expands the placeholders in value, the raw value of key.
+func (in *interpolator) resolve(key, value string) (string, error) {
+	key = joinKey(in.prefix, key)
+	if cached, ok := in.resolved[key]; ok {
+		return cached, nil
+	}
+	expanded, err := in.expand(value, []string{key})
+	if err != nil {
+		return "", err
+	}
+	in.resolved[key] = expanded
+	return expanded, nil
+}
+
+// lookup returns the expanded value of key. chain lists the keys being
+// resolved, outermost first, and is used to detect reference cycles.
+func (in *interpolator) lookup(key string, chain []string) (string, bool, error) {
+	for _, prev := range chain {
+		if prev == key {
+			return "", false, fmt.Errorf("placeholder cycle: %s -> %s", strings.Join(chain, " -> "), key)
+		}
+	}
+
+	if cached, ok := in.resolved[key]; ok {
+		return cached, true, nil
+	}
+
+	raw, ok := in.props.Get(key)
+	if !ok {
+		if in.envLookup {
+			if value, ok := lookupEnv(key); ok {
+				return value, true, nil
+			}
+		}
+		return "", false, nil
+	}
+
+	expanded, err := in.expand(raw, append(chain[:len(chain):len(chain)], key))
+	if err != nil {
+		return "", false, err
+	}
+	in.resolved[key] = expanded
+	return expanded, true, nil
+}
+
+// expand replaces every placeholder in s.
+func (in *interpolator) expand(s string, chain []string) (string, error) {
+	if !strings.Contains(s, "${") {
+		return s, nil
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(s); {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			sb.WriteString("${")
+			i += 3
+		case strings.HasPrefix(s[i:], "${"):
+			end := closingBrace(s, i+2)
+			if end < 0 {
+				return "", fmt.Errorf("unterminated placeholder in %q", s)
+			}
+			value, err := in.placeholder(s[i+2:end], chain)
+			if err != nil {
+				return "", err
+			}
+			sb.WriteString(value)
+			i = end + 1
+		default:
+			sb.WriteByte(s[i])
+			i++
+		}
+	}
+
+	return sb.String(), nil
+}
+
+// placeholder resolves the contents of a single ${...} placeholder.
+func (in *interpolator) placeholder(inner string, chain []string) (string, error) {
+	// Registered namespaces take precedence over ${key:default}
+	if namespace, rest, ok := strings.Cut(inner, ":"); ok {
+		if fn, ok := in.lookups[namespace]; ok {
+			return in.namespaced(namespace, fn, rest, chain)
+		}
+	}
+
+	name, def, hasDefault := splitDefault(inner)
+
+	// The name may itself contain placeholders
+	name, err := in.expand(name, chain)
+	if err != nil {
+		return "", err
+	}
+	if name == "" {
+		return "", errors.New("empty placeholder")
+	}
+
+	value, found, err := in.lookup(name, chain)
+	if err != nil {
+		return "", err
+	}
+	if found {
+		return value, nil
+	}
+	if hasDefault {
+		return in.expand(def, chain)
+	}
+	return "", fmt.Errorf("unresolved placeholder ${%s}", name)
+}
+
+// closingBrace returns the index of the "}" that closes a placeholder whose
+// contents start at start, or -1.
+func closingBrace(s string, start int) int {
+	depth := 0
+	for i := start; i < len(s); i++ {
+		switch {
+		case strings.HasPrefix(s[i:], "$${"):
+			i += 2
+		case strings.HasPrefix(s[i:], "${"):
+			depth++
+			i++
+		case s[i] == '}':
+			if depth == 0 {
+				return i
+			}
+			depth--
+		}
+	}
+	return -1
+}
+
+// splitDefault splits placeholder contents at the first ":" outside nested
+// placeholders.
+func splitDefault(inner string) (name, def string, ok bool) {
+	depth := 0
+	for i := 0; i < len(inner); i++ {
+		switch {
+		case strings.HasPrefix(inner[i:], "${"):
+			depth++
+			i++
+		case inner[i] == '}':
+			depth--
+		case inner[i] == ':' && depth == 0:
+			return inner[:i], inner[i+1:], true
+		}
+	}
+	return inner, "", false
+}
+
+// lookupEnv looks up key as an environment variable, first as is and then
+// in upper case with dots and dashes replaced by underscores.
+func lookupEnv(key string) (string, bool) {
+	if value, ok := os.LookupEnv(key); ok {
+		return value, true
+	}
+	return os.LookupEnv(strings.ToUpper(strings.NewReplacer(".", "_", "-", "_").Replace(key)))
+}
@@ -0,0 +1,168 @@
+package dotprops
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestUnmarshalInterpolation(t *testing.T) {
+	type Config struct {
+		Home    string `property:"app.home"`
+		Logs    string `property:"app.logs"`
+		Port    int    `property:"app.port"`
+		URL     string `property:"app.url"`
+		Literal string `property:"app.literal"`
+		Nested  string `property:"app.nested"`
+	}
+
+	data := []byte(`
+app.home=/opt/app
+app.logs=${app.home}/logs
+app.port=${server.port:8080}
+app.url=http://${app.host:localhost}:${app.port}/
+app.literal=$${app.home} is ${app.home}
+env=prod
+prod.name=Production
+app.nested=${${env}.name:${app.home}}
+`)
+
+	var config Config
+	err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.Logs != "/opt/app/logs" {
+		t.Errorf("Expected Logs '/opt/app/logs', got '%s'", config.Logs)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Expected Port 8080, got %d", config.Port)
+	}
+	if config.URL != "http://localhost:8080/" {
+		t.Errorf("Expected URL 'http://localhost:8080/', got '%s'", config.URL)
+	}
+	if config.Literal != "${app.home} is /opt/app" {
+		t.Errorf("Expected Literal '${app.home} is /opt/app', got '%s'", config.Literal)
+	}
+	if config.Nested != "Production" {
+		t.Errorf("Expected Nested 'Production', got '%s'", config.Nested)
+	}
+}
+
+func TestUnmarshalInterpolationCycle(t *testing.T) {
+	type Config struct {
+		A string `property:"a"`
+	}
+
+	data := []byte(`
+a=${b}
+b=x${c}
+c=${b}
+`)
+
+	var config Config
+	err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to a placeholder cycle, but it did not")
+	}
+	if !strings.Contains(err.Error(), "placeholder cycle: a -> b -> c -> b") {
+		t.Errorf("Expected error to show the full chain, got: %v", err)
+	}
+}
+
+func TestUnmarshalInterpolationUnresolved(t *testing.T) {
+	type Config struct {
+		A string `property:"a"`
+	}
+
+	dec := NewDecoder(WithInterpolation(true))
+
+	var config Config
+	err := dec.Unmarshal([]byte("a=${missing}\n"), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to an unresolved placeholder, but it did not")
+	}
+	if !strings.Contains(err.Error(), "unresolved placeholder ${missing}") {
+		t.Errorf("Expected error to name the placeholder, got: %v", err)
+	}
+
+	err = dec.Unmarshal([]byte("a=${unterminated\n"), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to an unterminated placeholder, but it did not")
+	}
+}
+
+func TestDecoderEnvLookup(t *testing.T) {
+	type Config struct {
+		Port int    `property:"port"`
+		Home string `property:"home"`
+	}
+
+	t.Setenv("PORT", "9000")
+	t.Setenv("APP_HOME", "/srv/app")
+
+	data := []byte(`
+port=${PORT:8080}
+home=${app.home}
+`)
+
+	var config Config
+	if err := NewDecoder(WithInterpolation(true), WithEnvLookup()).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Port != 9000 {
+		t.Errorf("Expected Port 9000, got %d", config.Port)
+	}
+	if config.Home != "/srv/app" {
+		t.Errorf("Expected Home '/srv/app', got '%s'", config.Home)
+	}
+
+	// Without env lookup the default applies and app.home is unresolved
+	err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail without env lookup, but it did not")
+	}
+}
+
+func TestUnmarshalWithoutInterpolation(t *testing.T) {
+	type Config struct {
+		A   string `property:"a"`
+		Dir string `property:"dir"`
+	}
+
+	// Placeholders are plain text unless interpolation is enabled
+	var config Config
+	if err := Unmarshal([]byte("a=a${b}\ndir=pa${ss\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.A != "a${b}" || config.Dir != "pa${ss" {
+		t.Errorf("Expected {a${b} pa${ss}, got %+v", config)
+	}
+
+	if err := NewDecoder(WithInterpolation(false)).Unmarshal([]byte("a=${b}\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.A != "${b}" {
+		t.Errorf("Expected A '${b}', got '%s'", config.A)
+	}
+}
+
+func TestLoaderInterpolationAcrossLayers(t *testing.T) {
+	type Config struct {
+		Logs string `property:"app.logs"`
+	}
+
+	loader := NewLoader(
+		Bytes("base", []byte("app.home=/opt/app\napp.logs=${app.home}/logs\n")),
+		Bytes("override", []byte("app.home=/srv/app\n")),
+	)
+	loader.Decoder = NewDecoder(WithInterpolation(true))
+
+	var config Config
+	if _, err := loader.Load(&config); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if config.Logs != "/srv/app/logs" {
+		t.Errorf("Expected Logs '/srv/app/logs', got '%s'", config.Logs)
+	}
+}
@@ -0,0 +1,375 @@
+package dotprops
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io/fs"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Source supplies one layer of properties to a Loader.
+type Source interface {
+	// Name identifies the source in error messages.
+	Name() string
+	// Load reads the properties provided by the source.
+	Load() (*Properties, error)
+}
+
+// Loader merges an ordered list of sources into a single property set.
+// Later sources take precedence over earlier ones.
+type Loader struct {
+	// Decoder decodes the merged properties in Load. If nil, a Decoder with
+	// default options is used.
+	Decoder *Decoder
+
+	sources []Source
+}
+
+// NewLoader returns a Loader for the given sources, lowest precedence first.
+func NewLoader(sources ...Source) *Loader {
+	return &Loader{sources: sources}
+}
+
+// Add appends sources to the loader. They take precedence over the sources
+// already added.
+func (l *Loader) Add(sources ...Source) *Loader {
+	l.sources = append(l.sources, sources...)
+	return l
+}
+
+// Properties merges every source and returns the result. The sources that
+// parse properties data read it in the syntax of the Decoder; see
+// WithDecodeSyntax.
+func (l *Loader) Properties() (*Properties, error) {
+	dec := l.Decoder
+	if dec == nil {
+		dec = NewDecoder()
+	}
+	return l.merge(NewProperties(), dec.syntax)
+}
+
+// Load merges every source on top of the `default` tags of v, decodes the
+// result into v and returns the merged properties. v must be a pointer to a
+// struct. If the Decoder uses WithOverlay, the `default` tags are left out.
+//
+// The defaults of a struct behind a pointer are only added when the sources
+// set a key below it, so that nil pointers stay nil.
+func (l *Loader) Load(v interface{}) (*Properties, error) {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return nil, errors.New("load expects a pointer to a struct")
+	}
+
+	dec := l.Decoder
+	if dec == nil {
+		dec = NewDecoder()
+	}
+
+	p, err := l.merge(NewProperties(), dec.syntax)
+	if err != nil {
+		return nil, err
+	}
+	if !dec.overlay {
+		base := tagDefaults(val.Elem().Type(), p)
+		base.Merge(p)
+		p = base
+	}
+
+	return p, dec.decode(p, val.Elem())
+}
+
+// merge loads every source in order, parsing data in the given syntax, and
+// merges it into p.
+func (l *Loader) merge(p *Properties, syntax Syntax) (*Properties, error) {
+	for _, src := range l.sources {
+		layer, err := loadSource(src, syntax)
+		if err != nil {
+			return nil, fmt.Errorf("error loading %s: %w", src.Name(), err)
+		}
+		p.Merge(layer)
+	}
+	return p, nil
+}
+
+// syntaxSource is implemented by the sources that parse properties data, which
+// Loader reads in the syntax of its Decoder. Their Load uses PlainSyntax.
+type syntaxSource interface {
+	loadSyntax(syntax Syntax) (*Properties, error)
+}
+
+// loadSource loads src, in the given syntax if it parses properties data.
+func loadSource(src Source, syntax Syntax) (*Properties, error) {
+	if s, ok := src.(syntaxSource); ok {
+		return s.loadSyntax(syntax)
+	}
+	return src.Load()
+}
+
+// Bytes returns a source that parses data. name is used in error messages.
+func Bytes(name string, data []byte) Source {
+	return &bytesSource{name: name, data: data}
+}
+
+type bytesSource struct {
+	name string
+	data []byte
+}
+
+func (s *bytesSource) Name() string { return s.name }
+
+func (s *bytesSource) Load() (*Properties, error) {
+	return s.loadSyntax(PlainSyntax)
+}
+
+func (s *bytesSource) loadSyntax(syntax Syntax) (*Properties, error) {
+	return parse(s.name, s.data, syntax)
+}
+
+// File returns a source that reads the properties file at path. Include
+// directives in the file are resolved relative to its directory.
+func File(path string) Source {
+	return &fileSource{path: path, files: &fileReader{}}
+}
+
+// FS returns a source that reads the properties file at path within fsys.
+// Include directives are resolved within fsys relative to the file.
+func FS(fsys fs.FS, path string) Source {
+	return &fileSource{path: path, files: &fileReader{fsys: fsys}}
+}
+
+type fileSource struct {
+	path  string
+	files *fileReader
+}
+
+func (s *fileSource) Name() string { return s.path }
+
+func (s *fileSource) Load() (*Properties, error) {
+	return s.loadSyntax(PlainSyntax)
+}
+
+func (s *fileSource) loadSyntax(syntax Syntax) (*Properties, error) {
+	return s.files.withSyntax(syntax).load(s.path)
+}
+
+// Env returns a source that reads environment variables starting with
+// prefix followed by an underscore. The remainder of the variable name is
+// lowercased and underscores are replaced with dots, so with prefix "APP"
+// the variable APP_SERVER_PORT provides the key server.port. Keys containing
+// upper-case letters or underscores, such as maxConns or max_conns, cannot be
+// set from the environment.
+func Env(prefix string) Source {
+	return &envSource{prefix: prefix}
+}
+
+type envSource struct {
+	prefix string
+}
+
+func (s *envSource) Name() string { return "env" }
+
+func (s *envSource) Load() (*Properties, error) {
+	p := NewProperties()
+	environ := os.Environ()
+	sort.Strings(environ)
+	for _, kv := range environ {
+		name, value, _ := strings.Cut(kv, "=")
+		if key, ok := envKey(s.prefix, name); ok {
+			p.set(key, value, Origin{Source: "env", Name: name})
+		}
+	}
+	return p, nil
+}
+
+// envKey maps an environment variable name to a property key.
+func envKey(prefix, name string) (string, bool) {
+	if prefix != "" {
+		if !strings.HasPrefix(name, prefix+"_") {
+			return "", false
+		}
+		name = name[len(prefix)+1:]
+	}
+	if name == "" {
+		return "", false
+	}
+	return strings.ToLower(strings.ReplaceAll(name, "_", ".")), true
+}
+
+// Flags returns a source that reads the flags of fs that were set on the
+// command line. The flag name is used as the property key.
+func Flags(fs *flag.FlagSet) Source {
+	return &flagSource{flags: fs}
+}
+
+type flagSource struct {
+	flags *flag.FlagSet
+}
+
+func (s *flagSource) Name() string { return "flags" }
+
+func (s *flagSource) Load() (*Properties, error) {
+	p := NewProperties()
+	s.flags.Visit(func(f *flag.Flag) {
+		p.set(f.Name, f.Value.String(), Origin{Source: "flags", Name: f.Name})
+	})
+	return p, nil
+}
+
+// Map returns a source that provides the entries of m in key order. name is
+// used in error messages.
+func Map(name string, m map[string]string) Source {
+	return &mapSource{name: name, m: m}
+}
+
+type mapSource struct {
+	name string
+	m    map[string]string
+}
+
+func (s *mapSource) Name() string { return s.name }
+
+func (s *mapSource) Load() (*Properties, error) {
+	keys := make([]string, 0, len(s.m))
+	for k := range s.m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	p := NewProperties()
+	for _, k := range keys {
+		p.set(k, s.m[k], Origin{Source: s.name})
+	}
+	return p, nil
+}
+
+// Optional wraps a source so that a missing file is treated as an empty
+// layer instead of an error.
+func Optional(src Source) Source {
+	return &optionalSource{Source: src}
+}
+
+type optionalSource struct {
+	Source
+}
+
+func (s *optionalSource) Load() (*Properties, error) {
+	return s.loadSyntax(PlainSyntax)
+}
+
+func (s *optionalSource) loadSyntax(syntax Syntax) (*Properties, error) {
+	p, err := loadSource(s.Source, syntax)
+	if errors.Is(err, fs.ErrNotExist) {
+		return NewProperties(), nil
+	}
+	return p, err
+}
+
+// Defaults returns a source that provides the `default` tags of the struct
+// pointed to by v. It is useful with Loader.Properties; Loader.Load adds the
+// defaults of its target automatically. The defaults of structs behind
+// pointers are left out.
+func Defaults(v interface{}) Source {
+	t := reflect.TypeOf(v)
+	for t != nil && t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	return &defaultsSource{typ: t}
+}
+
+type defaultsSource struct {
+	typ reflect.Type
+}
+
+func (s *defaultsSource) Name() string { return "defaults" }
+
+func (s *defaultsSource) Load() (*Properties, error) {
+	if s.typ == nil || s.typ.Kind() != reflect.Struct {
+		return nil, errors.New("defaults expects a struct or a pointer to a struct")
+	}
+	return tagDefaults(s.typ, nil), nil
+}
+
+// tagDefaults collects the `default` tags of a struct type, keyed by their full
+// property keys. The defaults below a pointer to a struct are only collected
+// if data has a key below the field or one of its aliases; data may be nil.
+func tagDefaults(t reflect.Type, data *Properties) *Properties {
+	p := NewProperties()
+	collectDefaults("", t, data, p, make(map[reflect.Type]bool))
+	return p
+}
+
+// collectDefaults walks the fields of t the same way setStructFields does.
+// visiting guards against self-referential types.
+func collectDefaults(prefix string, t reflect.Type, data, p *Properties, visiting map[reflect.Type]bool) {
+	if visiting[t] {
+		return
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+
+	for i := 0; i < t.NumField(); i++ {
+		fieldType := t.Field(i)
+
+		// Skip unexported fields and those tagged "-"
+		if !fieldType.IsExported() || skipped(fieldType) {
+			continue
+		}
+
+		ft := fieldType.Type
+		structType := ft
+		if structType.Kind() == reflect.Ptr {
+			structType = structType.Elem()
+		}
+
+		// Embedded structs share the prefix of their parent
+		if fieldType.Anonymous {
+			if structType.Kind() == reflect.Struct {
+				collectDefaults(prefix, structType, data, p, visiting)
+			}
+			continue
+		}
+
+		fullKey := joinKey(prefix, propertyKey(fieldType))
+
+		if def, ok := fieldType.Tag.Lookup("default"); ok {
+			origin := Origin{Source: "defaults", Name: t.Name() + "." + fieldType.Name}
+			p.put(fullKey, &entry{value: def, origin: origin, fromTag: true})
+			continue
+		}
+
+		// Descend into nested structs unless the field decodes itself.
+		// Defaults would allocate a nil pointer, so only descend into one
+		// that data sets.
+		if structType.Kind() == reflect.Struct && !decodesItself(structType) {
+			if ft.Kind() == reflect.Ptr && !setsBelow(data, prefix, fullKey, fieldType) {
+				continue
+			}
+			collectDefaults(fullKey, structType, data, p, visiting)
+		}
+	}
+}
+
+// setsBelow reports whether data has a key below fullKey, or below one of the
+// alternative keys of the field.
+func setsBelow(data *Properties, prefix, fullKey string, field reflect.StructField) bool {
+	if data == nil {
+		return false
+	}
+	keys := []string{fullKey}
+	_, opts := parseTag(field)
+	for _, old := range append(opts.values("alias"), opts.values("deprecated")...) {
+		keys = append(keys, joinKey(prefix, old))
+	}
+	for _, key := range data.keys {
+		for _, k := range keys {
+			if strings.HasPrefix(key, k+".") {
+				return true
+			}
+		}
+	}
+	return false
+}
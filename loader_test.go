@@ -0,0 +1,191 @@
+package dotprops
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestLoaderPrecedence(t *testing.T) {
+	type Config struct {
+		Name  string `property:"app.name" default:"DefaultApp"`
+		Port  int    `property:"app.port" default:"80"`
+		Debug bool   `property:"app.debug"`
+		Host  string `property:"app.host" default:"localhost"`
+	}
+
+	dir := t.TempDir()
+	base := filepath.Join(dir, "base.properties")
+	if err := os.WriteFile(base, []byte("app.name=BaseApp\napp.port=8080\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	fsys := fstest.MapFS{
+		"prod.properties": {Data: []byte("app.port=9090\napp.debug=true\n")},
+	}
+
+	t.Setenv("MYAPP_APP_PORT", "9191")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("app.name", "", "")
+	if err := flags.Parse([]string{"-app.name=FlagApp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(
+		File(base),
+		FS(fsys, "prod.properties"),
+		Env("MYAPP"),
+		Flags(flags),
+	)
+
+	var config Config
+	props, err := loader.Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.Name != "FlagApp" {
+		t.Errorf("Expected Name 'FlagApp', got '%s'", config.Name)
+	}
+	if config.Port != 9191 {
+		t.Errorf("Expected Port 9191, got %d", config.Port)
+	}
+	if config.Debug != true {
+		t.Errorf("Expected Debug true, got %v", config.Debug)
+	}
+	if config.Host != "localhost" {
+		t.Errorf("Expected Host 'localhost', got '%s'", config.Host)
+	}
+	if value, _ := props.Get("app.port"); value != "9191" {
+		t.Errorf("Expected merged app.port '9191', got '%s'", value)
+	}
+}
+
+func TestLoaderOptionalSource(t *testing.T) {
+	var config SimpleConfig
+	loader := NewLoader(
+		Bytes("base", []byte("app.name=BaseApp\n")),
+		Optional(File(filepath.Join(t.TempDir(), "missing.properties"))),
+	)
+
+	if _, err := loader.Load(&config); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if config.AppName != "BaseApp" {
+		t.Errorf("Expected AppName 'BaseApp', got '%s'", config.AppName)
+	}
+}
+
+func TestLoaderSyntax(t *testing.T) {
+	fsys := fstest.MapFS{
+		"app.properties": {Data: []byte("app.name: \\\n    FileApp\n")},
+	}
+	loader := NewLoader(
+		Bytes("base", []byte("app.name: BaseApp\n")),
+		Optional(FS(fsys, "app.properties")),
+	)
+
+	// Without JavaSyntax, ":" does not separate keys from values
+	props, err := loader.Properties()
+	if err != nil {
+		t.Fatalf("Properties failed: %v", err)
+	}
+	if _, ok := props.Get("app.name"); ok {
+		t.Errorf("Expected no app.name in PlainSyntax, got %v", props.Keys())
+	}
+
+	loader.Decoder = NewDecoder(WithDecodeSyntax(JavaSyntax))
+	var config SimpleConfig
+	if _, err := loader.Load(&config); err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if config.AppName != "FileApp" {
+		t.Errorf("Expected AppName 'FileApp', got '%s'", config.AppName)
+	}
+}
+
+func TestLoaderRequiredSourceMissing(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.properties")
+
+	var config SimpleConfig
+	_, err := NewLoader(File(missing)).Load(&config)
+	if err == nil {
+		t.Fatal("Expected Load to fail for a missing required file, but it did not")
+	}
+	if !strings.Contains(err.Error(), missing) {
+		t.Errorf("Expected error to name %s, got: %v", missing, err)
+	}
+}
+
+func TestLoaderMapAndAdd(t *testing.T) {
+	loader := NewLoader(Map("defaults", map[string]string{
+		"app.name": "MapApp",
+		"app.port": "1000",
+	}))
+	loader.Add(Bytes("override", []byte("app.port=2000\n")))
+
+	props, err := loader.Properties()
+	if err != nil {
+		t.Fatalf("Properties failed: %v", err)
+	}
+
+	expected := []string{"app.name", "app.port"}
+	if keys := props.Keys(); strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
+	}
+	if value, _ := props.Get("app.port"); value != "2000" {
+		t.Errorf("Expected app.port '2000', got '%s'", value)
+	}
+}
+
+func TestLoaderNonPointer(t *testing.T) {
+	var config SimpleConfig
+	if _, err := NewLoader().Load(config); err == nil {
+		t.Fatal("Expected Load to fail for a non-pointer target, but it did not")
+	}
+}
+
+func TestEnvKey(t *testing.T) {
+	tests := []struct {
+		prefix, name, key string
+		ok                bool
+	}{
+		{"APP", "APP_SERVER_PORT", "server.port", true},
+		{"APP", "OTHER_SERVER_PORT", "", false},
+		{"APP", "APP_", "", false},
+		{"", "HOME", "home", true},
+	}
+
+	for _, tt := range tests {
+		key, ok := envKey(tt.prefix, tt.name)
+		if key != tt.key || ok != tt.ok {
+			t.Errorf("envKey(%q, %q) = %q, %v; expected %q, %v", tt.prefix, tt.name, key, ok, tt.key, tt.ok)
+		}
+	}
+}
+
+func TestDefaultsSource(t *testing.T) {
+	type Node struct {
+		Name string `property:"name" default:"root"`
+		Next *Node  `property:"next"`
+	}
+	type Config struct {
+		Database DatabaseConfig `property:"database"`
+		Node     Node           `property:"node"`
+		Timeout  int            `property:"timeout" default:"30"`
+	}
+
+	props, err := Defaults(&Config{}).Load()
+	if err != nil {
+		t.Fatalf("Defaults failed: %v", err)
+	}
+
+	expected := []string{"node.name", "timeout"}
+	if keys := props.Keys(); strings.Join(keys, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected keys %v, got %v", expected, keys)
+	}
+}
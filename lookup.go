@@ -0,0 +1,132 @@
+package dotprops
+
+import (
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"time"
+)
+
+// ErrNotFound is returned by a Lookup when its argument does not resolve to
+// a value. It lets a ${namespace:argument:-default} placeholder fall back to
+// its default.
+var ErrNotFound = errors.New("not found")
+
+// Lookup resolves the argument of a ${namespace:argument} placeholder.
+type Lookup func(arg string) (string, error)
+
+// WithLookup registers fn for placeholders of the form ${namespace:argument},
+// replacing any lookup already registered for namespace.
+func WithLookup(namespace string, fn Lookup) DecoderOption {
+	return func(d *Decoder) {
+		d.lookups[namespace] = fn
+	}
+}
+
+// WithoutLookup disables the given namespaces, for example "env" and "file"
+// when values may come from untrusted sources. Placeholders using a disabled
+// namespace fail to resolve.
+func WithoutLookup(namespaces ...string) DecoderOption {
+	return func(d *Decoder) {
+		for _, namespace := range namespaces {
+			d.lookups[namespace] = disabledLookup
+		}
+	}
+}
+
+func disabledLookup(string) (string, error) {
+	return "", errors.New("namespace is disabled")
+}
+
+// defaultLookups returns the namespaces available to every Decoder:
+//
+//	${env:NAME}         the environment variable NAME
+//	${file:PATH}        the contents of the file at PATH with surrounding
+//	                    whitespace trimmed, e.g. a Docker or Kubernetes secret
+//	${base64:DATA}      the standard base64 decoding of DATA
+//	${date:LAYOUT}      the current time formatted with the time package
+//	                    LAYOUT, or RFC 3339 if LAYOUT is empty
+func defaultLookups() map[string]Lookup {
+	return map[string]Lookup{
+		"env":    envLookup,
+		"file":   fileLookup,
+		"base64": base64Lookup,
+		"date":   dateLookup,
+	}
+}
+
+func envLookup(name string) (string, error) {
+	value, ok := os.LookupEnv(name)
+	if !ok {
+		return "", ErrNotFound
+	}
+	return value, nil
+}
+
+func fileLookup(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return "", ErrNotFound
+	}
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSpace(string(data)), nil
+}
+
+func base64Lookup(data string) (string, error) {
+	decoded, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return "", err
+	}
+	return string(decoded), nil
+}
+
+func dateLookup(layout string) (string, error) {
+	if layout == "" {
+		layout = time.RFC3339
+	}
+	return time.Now().Format(layout), nil
+}
+
+// namespaced resolves a ${namespace:argument} placeholder with fn. rest is
+// everything after the namespace; a ":-" outside nested placeholders
+// separates an optional default.
+func (in *interpolator) namespaced(namespace string, fn Lookup, rest string, chain []string) (string, error) {
+	arg, def, hasDefault := splitNamespaceDefault(rest)
+
+	// The argument may itself contain placeholders
+	arg, err := in.expand(arg, chain)
+	if err != nil {
+		return "", err
+	}
+
+	value, err := fn(arg)
+	if errors.Is(err, ErrNotFound) && hasDefault {
+		return in.expand(def, chain)
+	}
+	if err != nil {
+		return "", fmt.Errorf("%s lookup %q: %v", namespace, arg, err)
+	}
+	return value, nil
+}
+
+// splitNamespaceDefault splits the argument of a namespaced placeholder at
+// the first ":-" outside nested placeholders.
+func splitNamespaceDefault(rest string) (arg, def string, ok bool) {
+	depth := 0
+	for i := 0; i < len(rest); i++ {
+		switch {
+		case strings.HasPrefix(rest[i:], "${"):
+			depth++
+			i++
+		case rest[i] == '}':
+			depth--
+		case strings.HasPrefix(rest[i:], ":-") && depth == 0:
+			return rest[:i], rest[i+2:], true
+		}
+	}
+	return rest, "", false
+}
@@ -0,0 +1,111 @@
+package dotprops
+
+import (
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLookupNamespaces(t *testing.T) {
+	type Config struct {
+		Home     string `property:"home"`
+		Password string `property:"db.password"`
+		Token    string `property:"token"`
+		Year     string `property:"year"`
+		Port     int    `property:"port"`
+	}
+
+	secret := filepath.Join(t.TempDir(), "db")
+	if err := os.WriteFile(secret, []byte("s3cr3t\n"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("TEST_HOME", "/home/test")
+
+	data := []byte(`
+home=${env:TEST_HOME}
+secret.path=` + secret + `
+db.password=${file:${secret.path}}
+token=${base64:aGVsbG8=}
+year=${date:2006}
+port=${env:TEST_PORT:-8080}
+`)
+
+	var config Config
+	if err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.Home != "/home/test" {
+		t.Errorf("Expected Home '/home/test', got '%s'", config.Home)
+	}
+	if config.Password != "s3cr3t" {
+		t.Errorf("Expected Password 's3cr3t', got '%s'", config.Password)
+	}
+	if config.Token != "hello" {
+		t.Errorf("Expected Token 'hello', got '%s'", config.Token)
+	}
+	if config.Year != strconv.Itoa(time.Now().Year()) {
+		t.Errorf("Expected Year %d, got '%s'", time.Now().Year(), config.Year)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Expected Port 8080, got %d", config.Port)
+	}
+}
+
+func TestLookupErrors(t *testing.T) {
+	type Config struct {
+		Value string `property:"value"`
+	}
+
+	tests := []struct {
+		data     string
+		contains string
+	}{
+		{"value=${env:DOTPROPS_UNSET_VARIABLE}", "env lookup \"DOTPROPS_UNSET_VARIABLE\": not found"},
+		{"value=${base64:!!!}", "base64 lookup \"!!!\""},
+	}
+
+	for _, tt := range tests {
+		var config Config
+		err := NewDecoder(WithInterpolation(true)).Unmarshal([]byte(tt.data), &config)
+		if err == nil {
+			t.Errorf("Expected Unmarshal of %q to fail, but it did not", tt.data)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.contains) {
+			t.Errorf("Expected error to contain %q, got: %v", tt.contains, err)
+		}
+	}
+}
+
+func TestCustomAndDisabledLookups(t *testing.T) {
+	type Config struct {
+		Value string `property:"value"`
+	}
+
+	upper := func(arg string) (string, error) {
+		return strings.ToUpper(arg), nil
+	}
+
+	var config Config
+	decoder := NewDecoder(WithInterpolation(true), WithLookup("upper", upper))
+	if err := decoder.Unmarshal([]byte("value=${upper:abc}"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Value != "ABC" {
+		t.Errorf("Expected Value 'ABC', got '%s'", config.Value)
+	}
+
+	t.Setenv("TEST_HOME", "/home/test")
+	decoder = NewDecoder(WithInterpolation(true), WithoutLookup("env", "file"))
+	err := decoder.Unmarshal([]byte("value=${env:TEST_HOME}"), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail with a disabled namespace, but it did not")
+	}
+	if !strings.Contains(err.Error(), "namespace is disabled") {
+		t.Errorf("Expected error to mention the disabled namespace, got: %v", err)
+	}
+}
@@ -1,62 +1,148 @@
 package dotprops
 
 import (
+	"encoding/json"
 	"fmt"
 	"reflect"
-	"sort"
-	"strings"
+	"time"
 )
 
-// Marshal returns the properties encoding of v.
+// Encoder encodes structs as properties. Use NewEncoder to create one.
+type Encoder struct {
+	redact      bool
+	encodeFuncs map[reflect.Type]EncodeFunc
+
+	order     KeyOrder
+	less      func(a, b string) bool
+	header    []string
+	timestamp bool
+	sections  bool
+	now       func() time.Time
+
+	syntax            Syntax
+	separator         string
+	align             bool
+	lineEnding        string
+	noTrailingNewline bool
+	maxWidth          int
+
+	fallback Fallback
+	nils     NilMode
+	maxDepth int
+	prune    bool
+}
+
+// EncoderOption configures an Encoder.
+type EncoderOption func(*Encoder)
+
+// WithRedaction replaces the values of fields tagged secret or sensitive
+// with ****** in the output.
+func WithRedaction() EncoderOption {
+	return func(e *Encoder) {
+		e.redact = true
+	}
+}
+
+// NewEncoder returns an Encoder configured with opts.
+func NewEncoder(opts ...EncoderOption) *Encoder {
+	e := &Encoder{now: time.Now, separator: "=", lineEnding: "\n"}
+	for _, opt := range opts {
+		opt(e)
+	}
+	return e
+}
+
+// Marshal returns the properties encoding of v, using the default Encoder.
 // v must be a struct or a pointer to a struct.
 func Marshal(v interface{}) ([]byte, error) {
+	return NewEncoder().Marshal(v)
+}
+
+// Marshal returns the properties encoding of v.
+// v must be a struct or a pointer to a struct.
+func (e *Encoder) Marshal(v interface{}) ([]byte, error) {
+	val, err := structValue(v)
+	if err != nil {
+		return nil, err
+	}
+
+	props, es, err := e.encode(val)
+	if err != nil {
+		return nil, err
+	}
+
+	return e.write(e.sortKeys(props.Keys()), props, es.comments, es.commented), nil
+}
+
+// structValue returns the struct v is or points to.
+func structValue(v interface{}) (reflect.Value, error) {
 	val := reflect.ValueOf(v)
 	if val.Kind() == reflect.Ptr {
 		if val.Elem().Kind() != reflect.Struct {
-			return nil, fmt.Errorf("marshal expects a pointer to a struct")
+			return reflect.Value{}, fmt.Errorf("marshal expects a pointer to a struct")
 		}
 		val = val.Elem()
 	} else if val.Kind() != reflect.Struct {
-		return nil, fmt.Errorf("marshal expects a struct or a pointer to a struct")
+		return reflect.Value{}, fmt.Errorf("marshal expects a struct or a pointer to a struct")
 	}
+	return val, nil
+}
 
-	// Ensure the value is addressable
-	if !val.CanAddr() {
-		return nil, fmt.Errorf("marshal requires an addressable struct to handle TextMarshaler")
+// encode encodes the fields of the struct val as properties.
+func (e *Encoder) encode(val reflect.Value) (*Properties, *encodeState, error) {
+	props := NewProperties()
+	es := &encodeState{
+		redact:      e.redact,
+		encodeFuncs: e.encodeFuncs,
+		comments:    make(map[string]string),
+		fallback:    e.fallback,
+		nils:        e.nils,
+		commented:   make(map[string]bool),
+		path:        make(map[visitKey]string),
+		maxDepth:    e.maxDepth,
 	}
-
-	props := make(map[string]string)
-	err := encodeStruct("", val, props)
-	if err != nil {
-		return nil, err
+	if val.CanAddr() {
+		es.path[visitKey{val.Addr().Pointer(), val.Addr().Type()}] = ""
 	}
-
-	// Sort the keys for consistent output
-	keys := make([]string, 0, len(props))
-	for k := range props {
-		keys = append(keys, k)
-	}
-	sort.Strings(keys)
-
-	// Build the properties string
-	var sb strings.Builder
-	for _, k := range keys {
-		sb.WriteString(fmt.Sprintf("%s=%s\n", k, props[k]))
+	if err := es.encodeStruct("", val, props); err != nil {
+		return nil, nil, err
 	}
+	return props, es, nil
+}
 
-	return []byte(sb.String()), nil
+// encodeState holds the state of a single encode pass.
+type encodeState struct {
+	// redact replaces sensitive values with ******.
+	redact bool
+	// secret is set while encoding below a sensitive field.
+	secret bool
+	// encodeFuncs convert values before the built-in encoding.
+	encodeFuncs map[reflect.Type]EncodeFunc
+	// comments holds the `comment` and `doc` tags by full key.
+	comments map[string]string
+	// fallback enables the Stringer and JSON encoding of values.
+	fallback Fallback
+	// path holds the pointers being encoded, by the key they were first
+	// followed at; depth is the current struct nesting depth.
+	path     map[visitKey]string
+	depth    int
+	maxDepth int
+	// nils selects how nil values are written; commented holds the keys
+	// written as comments.
+	nils      NilMode
+	commented map[string]bool
 }
 
 // encodeStruct encodes a struct into the props map with proper key prefixes
-func encodeStruct(prefix string, val reflect.Value, props map[string]string) error {
+func (e *encodeState) encodeStruct(prefix string, val reflect.Value, props *Properties) error {
 	valType := val.Type()
 
 	for i := 0; i < val.NumField(); i++ {
 		field := val.Field(i)
 		fieldType := valType.Field(i)
 
-		// Skip unexported fields
-		if !field.CanInterface() {
+		// Skip unexported fields and those tagged "-"
+		if !field.CanInterface() || skipped(fieldType) {
 			continue
 		}
 
@@ -64,7 +150,7 @@ func encodeStruct(prefix string, val reflect.Value, props map[string]string) err
 		isEmbedded := fieldType.Anonymous
 
 		// Get the property key from the struct tag or use the field name
-		propertyKey := fieldType.Tag.Get("property")
+		propertyKey, _ := parseTag(fieldType)
 		if propertyKey == "" && !isEmbedded {
 			propertyKey = fieldType.Name
 		}
@@ -79,65 +165,172 @@ func encodeStruct(prefix string, val reflect.Value, props map[string]string) err
 			fullKey = propertyKey
 		}
 
-		// Handle pointer types
-		if field.Kind() == reflect.Ptr {
-			if field.IsNil() {
-				continue // Skip nil pointers
-			}
-			field = field.Elem()
+		// Record the documentation of the field, written above its keys
+		if doc := fieldComment(fieldType); doc != "" && !isEmbedded {
+			e.comments[fullKey] = doc
 		}
 
-		// Check if the field implements PropMarshaler
-		if pm, ok := field.Addr().Interface().(PropMarshaler); ok {
-			key, value, err := pm.MarshalProp()
-			if err != nil {
-				return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
-			}
-			props[key] = value
+		// Nil embedded structs have no key of their own to write
+		if isEmbedded && field.Kind() == reflect.Ptr && field.IsNil() {
 			continue
 		}
 
-		// Check if the field implements TextMarshaler
-		if field.CanInterface() {
-			if marshaler, ok := field.Addr().Interface().(TextMarshaler); ok {
-				text, err := marshaler.MarshalText()
-				if err != nil {
-					return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
-				}
-				props[fullKey] = string(text)
-				continue
-			}
-		}
-
-		switch field.Kind() {
-		case reflect.Struct:
-			if isEmbedded {
-				// For embedded structs, continue with the same prefix
-				err := encodeStruct(fullKey, field, props)
-				if err != nil {
-					return err
-				}
-			} else {
-				// For nested structs, use the new prefix
-				err := encodeStruct(fullKey, field, props)
-				if err != nil {
-					return err
-				}
-			}
-		case reflect.String:
-			props[fullKey] = field.String()
-		case reflect.Bool:
-			props[fullKey] = fmt.Sprintf("%v", field.Bool())
-		case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-			props[fullKey] = fmt.Sprintf("%d", field.Int())
-		case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-			props[fullKey] = fmt.Sprintf("%d", field.Uint())
-		case reflect.Float32, reflect.Float64:
-			props[fullKey] = fmt.Sprintf("%f", field.Float())
-		default:
-			return fmt.Errorf("unsupported field type: %s for field %s", field.Kind(), fullKey)
+		// Values of sensitive fields are replaced when redacting
+		sensitive := e.secret || (!isEmbedded && isSensitive(fieldType))
+
+		err := e.encodeField(field, fieldType, fullKey, sensitive, props)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
+
+// encodeField encodes the value of a struct field, or of a map entry, under
+// fullKey. Values are resolved in order through an EncodeFunc, RawProps,
+// PropsMarshaler, PropMarshaler, TextMarshaler and the enabled fallbacks,
+// before the built-in encoding of structs, maps, slices and basic types.
+func (e *encodeState) encodeField(field reflect.Value, fieldType reflect.StructField, fullKey string, sensitive bool, props *Properties) error {
+	redact := e.redact && sensitive
+
+	// Handle interface fields through the type registry, or else encode
+	// the value they hold
+	if field.Kind() == reflect.Interface {
+		if field.IsNil() {
+			e.encodeNil(fullKey, props)
+			return nil
+		}
+		if hasRegisteredTypes(field.Type()) {
+			return e.encodeInterface(field, fieldType, fullKey, sensitive, props)
+		}
+		field = field.Elem()
+	}
+
+	// Handle pointer types
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			e.encodeNil(fullKey, props)
+			return nil
+		}
+		leave, err := e.visit(field, fullKey)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		field = field.Elem()
+	}
+
+	setValue := func(value string) {
+		if redact {
+			value = redactedValue
+		}
+		props.Set(fullKey, value)
+	}
+
+	// Registered converters take precedence
+	if fn, ok := e.encodeFunc(field.Type()); ok {
+		text, err := fn(field.Interface())
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		setValue(text)
+		return nil
+	}
+
+	// Write the captured sub-tree of RawProps fields
+	if field.Type() == rawPropsType {
+		e.encodeRaw(field.Interface().(RawProps), fullKey, redact, props)
+		return nil
+	}
+
+	// Check if the field encodes a whole sub-tree itself
+	if impl, ok := implementation(field, propsMarshalerType); ok {
+		return e.encodeSubtree(impl.(PropsMarshaler), fullKey, redact, props)
+	}
+
+	// Check if the field implements PropMarshaler
+	if impl, ok := implementation(field, propMarshalerType); ok {
+		pm := impl.(PropMarshaler)
+		key, value, err := pm.MarshalProp()
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		if redact {
+			value = redactedValue
+		}
+		props.Set(key, value)
+		return nil
+	}
+
+	// Check if the field implements TextMarshaler, or a fallback
+	if text, ok, err := e.marshalSelf(field); ok {
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		setValue(text)
+		return nil
+	}
+
+	format := fieldType.Tag.Get("format")
+	switch {
+	case field.Kind() == reflect.Struct:
+		// Embedded structs continue with the same prefix, nested structs
+		// with the new one
+		if fieldType.Anonymous {
+			return e.encodeStruct(fullKey, field, props)
+		}
+		return e.encodeNested(fullKey, field, sensitive, props)
+	case field.Kind() == reflect.Map && field.Type().Key().Kind() == reflect.String:
+		return e.encodeMap(field, fieldType, fullKey, sensitive, props)
+	case field.Kind() == reflect.Slice && e.encodesItem(field.Type().Elem()):
+		if field.IsNil() {
+			e.encodeNil(fullKey, props)
+			return nil
+		}
+		text, err := e.encodeList(field, format)
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		setValue(text)
+		return nil
+	case isScalar(field.Kind()):
+		text, err := e.marshalItem(field, format)
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		setValue(text)
+		return nil
+	case e.fallback&JSONFallback != 0:
+		text, err := json.Marshal(field.Interface())
+		if err != nil {
+			return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+		}
+		setValue(string(text))
+		return nil
+	}
+	return fmt.Errorf("unsupported field type: %s for field %s", field.Kind(), fullKey)
+}
+
+var (
+	propMarshalerType = reflect.TypeOf((*PropMarshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*TextMarshaler)(nil)).Elem()
+)
+
+// implementation returns v as a value of the interface type iface, if either v
+// or a pointer to v implements it. For pointer receivers, a value that is not
+// addressable is copied to a new variable first.
+func implementation(v reflect.Value, iface reflect.Type) (interface{}, bool) {
+	if v.Type().Implements(iface) {
+		return v.Interface(), true
+	}
+	if !reflect.PointerTo(v.Type()).Implements(iface) {
+		return nil, false
+	}
+	if v.CanAddr() {
+		return v.Addr().Interface(), true
+	}
+	ptr := reflect.New(v.Type())
+	ptr.Elem().Set(v)
+	return ptr.Interface(), true
+}
@@ -2,6 +2,7 @@ package dotprops
 
 import (
 	"errors"
+	"strings"
 	"testing"
 )
 
@@ -70,11 +71,11 @@ func TestMarshalOptionalFields(t *testing.T) {
 
 func TestMarshalUnsupportedType(t *testing.T) {
 	type UnsupportedConfig struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 
 	config := UnsupportedConfig{
-		Data: []string{"one", "two", "three"},
+		Data: make(chan int),
 	}
 
 	_, err := Marshal(config)
@@ -183,7 +184,7 @@ func TestMarshalWithUintAndFloatFields(t *testing.T) {
 		Threshold: 75.5,
 	}
 
-	expected := "max.users=1000\nthreshold=75.500000\n"
+	expected := "max.users=1000\nthreshold=75.5\n"
 
 	data, err := Marshal(config)
 	if err != nil {
@@ -250,7 +251,7 @@ func TestMarshalWithEmbeddedStruct(t *testing.T) {
 
 func TestMarshalWithUnsupportedNestedStruct(t *testing.T) {
 	type InnerUnsupported struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 
 	type OuterConfig struct {
@@ -261,7 +262,7 @@ func TestMarshalWithUnsupportedNestedStruct(t *testing.T) {
 	config := &OuterConfig{
 		Name: "Outer",
 		Inner: InnerUnsupported{
-			Data: []string{"one", "two"},
+			Data: make(chan int),
 		},
 	}
 
@@ -358,7 +359,7 @@ func TestMarshalWithCustomTextMarshaler(t *testing.T) {
 
 func TestMarshalWithUnsupportedNestedStructTypes(t *testing.T) {
 	type InnerUnsupported struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 	type OuterConfig struct {
 		Name  string           `property:"name"`
@@ -368,7 +369,7 @@ func TestMarshalWithUnsupportedNestedStructTypes(t *testing.T) {
 	config := &OuterConfig{
 		Name: "OuterService",
 		Inner: InnerUnsupported{
-			Data: []string{"one", "two"},
+			Data: make(chan int),
 		},
 	}
 
@@ -512,3 +513,76 @@ func TestMarshalWithPropMarshalerError(t *testing.T) {
 		t.Fatal("Expected Marshal to fail due to PropMarshaler error, but it did not")
 	}
 }
+
+// PointerText implements TextMarshaler with a pointer receiver
+type PointerText struct {
+	value string
+}
+
+func (p *PointerText) MarshalText() ([]byte, error) {
+	return []byte("ptr_" + p.value), nil
+}
+
+// PointerProp implements PropMarshaler with a pointer receiver
+type PointerProp struct {
+	value string
+}
+
+func (p *PointerProp) MarshalProp() (string, string, error) {
+	return "pointer.prop", p.value, nil
+}
+
+type ValueConfig struct {
+	Name    CustomString   `property:"name"`
+	Text    PointerText    `property:"text"`
+	Prop    PointerProp    `property:"prop"`
+	Nested  EndpointConfig `property:"nested"`
+	Any     interface{}    `property:"any"`
+	AnyPtr  interface{}    `property:"any.ptr"`
+	AnyText interface{}    `property:"any.text"`
+}
+
+func TestMarshalNonAddressableValue(t *testing.T) {
+	config := ValueConfig{
+		Name:    CustomString("value"),
+		Text:    PointerText{value: "text"},
+		Prop:    PointerProp{value: "prop"},
+		Nested:  EndpointConfig{URL: "http://x", Port: 80},
+		Any:     EndpointConfig{URL: "http://any"},
+		AnyPtr:  &EndpointConfig{Port: 443},
+		AnyText: PointerText{value: "any"},
+	}
+
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"any.active=false",
+		"any.port=0",
+		"any.ptr.active=false",
+		"any.ptr.port=443",
+		"any.ptr.url=",
+		"any.text=ptr_any",
+		"any.url=http://any",
+		"name=custom_value",
+		"nested.active=false",
+		"nested.port=80",
+		"nested.url=http://x",
+		"pointer.prop=prop",
+		"text=ptr_text",
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	// Marshaling a pointer gives the same result
+	data, err = Marshal(&config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
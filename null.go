@@ -0,0 +1,103 @@
+package dotprops
+
+import (
+	"reflect"
+	"strings"
+)
+
+// Null is the value of a property that is explicitly null. It is written for
+// nil pointers with NullNils, and read as the zero value of a field by a
+// Decoder created WithNullValues.
+const Null = "@null"
+
+// NilMode selects how Marshal writes nil pointers, interfaces and slices.
+type NilMode int
+
+const (
+	// OmittedNils leaves the keys of nil values out. This is the default.
+	OmittedNils NilMode = iota
+	// EmptyNils writes the keys of nil values with an empty value. Read
+	// them back as nil with WithEmptyAsAbsent.
+	EmptyNils
+	// CommentedNils writes the keys of nil values as comments, such as
+	// "# timeout=", to show the available keys.
+	CommentedNils
+	// NullNils writes the keys of nil values with the value Null. Read them
+	// back as nil with WithNullValues.
+	NullNils
+)
+
+// WithNilPointers sets how nil pointers, interfaces and slices are written.
+func WithNilPointers(mode NilMode) EncoderOption {
+	return func(e *Encoder) {
+		e.nils = mode
+	}
+}
+
+// WithEmptyAsAbsent treats empty values as if their keys were missing, so that
+// pointers stay nil and fields keep their defaults. A value the empty one
+// overrode, such as a `default` tag or a lower layer of a Loader, applies
+// instead.
+func WithEmptyAsAbsent() DecoderOption {
+	return func(d *Decoder) {
+		d.emptyAbsent = true
+	}
+}
+
+// WithNullValues reads the value Null as an explicit null: the field is set to
+// its zero value, nil for pointers, slices, maps and interfaces, overriding
+// any default.
+func WithNullValues() DecoderOption {
+	return func(d *Decoder) {
+		d.nulls = true
+	}
+}
+
+// present applies the null and empty value semantics to the value of
+// fullKey. It returns the value to decode, and false if there is none; an
+// explicit null has zeroed field by then.
+func (d *decodeState) present(field reflect.Value, fullKey string, value interface{}) (interface{}, bool) {
+	s, ok := value.(string)
+	if !ok {
+		return value, true
+	}
+
+	if d.isNull(s) {
+		field.Set(reflect.Zero(field.Type()))
+		return nil, false
+	}
+
+	if d.emptyAbsent && strings.TrimSpace(s) == "" {
+		// Fall back to the most recent value the empty one shadowed
+		if d.props != nil {
+			if e, ok := d.props.entries[fullKey]; ok {
+				for _, v := range e.shadowed {
+					if strings.TrimSpace(v.Text) != "" {
+						return d.present(field, fullKey, v.Text)
+					}
+				}
+			}
+		}
+		return nil, false
+	}
+	return value, true
+}
+
+// isNull reports whether value is an explicit null.
+func (d *decodeState) isNull(value interface{}) bool {
+	s, ok := value.(string)
+	return ok && d.nulls && strings.TrimSpace(s) == Null
+}
+
+// encodeNil writes the key of a nil value according to the NilMode.
+func (e *encodeState) encodeNil(fullKey string, props *Properties) {
+	switch e.nils {
+	case EmptyNils:
+		props.Set(fullKey, "")
+	case CommentedNils:
+		props.Set(fullKey, "")
+		e.commented[fullKey] = true
+	case NullNils:
+		props.Set(fullKey, Null)
+	}
+}
@@ -0,0 +1,158 @@
+package dotprops
+
+import (
+	"strings"
+	"testing"
+)
+
+type NullableConfig struct {
+	Name    *string `property:"name"`
+	Timeout *int    `property:"timeout"`
+	Retries int     `property:"retries" default:"3"`
+	TLS     *struct {
+		Cert string `property:"cert"`
+	} `property:"tls"`
+	Tags map[string]*string `property:"tags"`
+}
+
+func TestMarshalNilPointerModes(t *testing.T) {
+	name := "app"
+	config := NullableConfig{Name: &name, Retries: 3}
+
+	tests := []struct {
+		name     string
+		mode     NilMode
+		expected string
+	}{
+		{"omitted", OmittedNils, "name=app\nretries=3\n"},
+		{"empty", EmptyNils, "name=app\nretries=3\ntimeout=\ntls=\n"},
+		{"commented", CommentedNils, "name=app\nretries=3\n# timeout=\n# tls=\n"},
+		{"null", NullNils, "name=app\nretries=3\ntimeout=@null\ntls=@null\n"},
+	}
+
+	for _, tt := range tests {
+		data, err := NewEncoder(WithNilPointers(tt.mode)).Marshal(config)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", tt.name, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("%s: Expected:\n%s\nGot:\n%s", tt.name, tt.expected, data)
+		}
+	}
+
+	data, err := NewEncoder(WithNilPointers(CommentedNils), WithSeparator(" = ")).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "# timeout =\n") {
+		t.Errorf("Expected a commented key with the separator, got:\n%s", data)
+	}
+}
+
+func TestUnmarshalEmptyAsAbsent(t *testing.T) {
+	data := []byte("name=\ntimeout=\nretries=\ntls=\n")
+
+	var config NullableConfig
+	if err := Unmarshal(data, &config); err == nil {
+		t.Fatal("Expected Unmarshal to fail on an empty nested struct value, but it did not")
+	}
+
+	config = NullableConfig{}
+	if err := NewDecoder(WithEmptyAsAbsent(), WithTagDefaults()).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != nil || config.Timeout != nil || config.TLS != nil {
+		t.Errorf("Expected nil pointers, got %+v", config)
+	}
+	if config.Retries != 3 {
+		t.Errorf("Expected the default of Retries to apply, got %d", config.Retries)
+	}
+
+	// Lower layers of a Loader apply too
+	loader := NewLoader(
+		Map("base", map[string]string{"timeout": "30"}),
+		Bytes("override", []byte("timeout=\n")),
+	)
+	loader.Decoder = NewDecoder(WithEmptyAsAbsent())
+
+	var loaded NullableConfig
+	_, err := loader.Load(&loaded)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+	if loaded.Timeout == nil || *loaded.Timeout != 30 {
+		t.Errorf("Expected Timeout 30 from the lower layer, got %v", loaded.Timeout)
+	}
+}
+
+func TestUnmarshalNullValues(t *testing.T) {
+	data := []byte("name=@null\nretries=@null\ntls=@null\ntags.a=x\ntags.b=@null\n")
+
+	var config NullableConfig
+	if err := NewDecoder(WithNullValues(), WithTagDefaults()).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != nil || config.TLS != nil {
+		t.Errorf("Expected nil pointers, got %+v", config)
+	}
+	if config.Retries != 0 {
+		t.Errorf("Expected null to override the default of Retries, got %d", config.Retries)
+	}
+	if b, ok := config.Tags["b"]; !ok || b != nil {
+		t.Errorf("Expected an explicit nil entry for tags.b, got %v", config.Tags)
+	}
+	if a := config.Tags["a"]; a == nil || *a != "x" {
+		t.Errorf("Expected tags.a to be x, got %v", a)
+	}
+
+	// Without the option, the sentinel is an ordinary value
+	if err := Unmarshal([]byte("name=@null\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name == nil || *config.Name != Null {
+		t.Errorf("Expected Name to be %s, got %v", Null, config.Name)
+	}
+}
+
+func TestNullRoundTrip(t *testing.T) {
+	timeout := 10
+	config := NullableConfig{Timeout: &timeout, Retries: 5}
+
+	data, err := NewEncoder(WithNilPointers(NullNils)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	var decoded NullableConfig
+	if err := NewDecoder(WithNullValues()).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Name != nil || decoded.TLS != nil || decoded.Timeout == nil || *decoded.Timeout != 10 || decoded.Retries != 5 {
+		t.Errorf("Expected round trip to give %+v, got %+v", config, decoded)
+	}
+}
+
+func TestMarshalNilSlices(t *testing.T) {
+	type Config struct {
+		Name  string   `property:"name"`
+		Tags  []string `property:"tags"`
+		Empty []string `property:"empty"`
+	}
+	config := Config{Name: "app", Empty: []string{}}
+
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if expected := "empty=\nname=app\n"; string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	data, err = NewEncoder(WithNilPointers(NullNils)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "tags=@null\n") {
+		t.Errorf("Expected the nil slice as null, got:\n%s", data)
+	}
+}
@@ -0,0 +1,84 @@
+package dotprops
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// formatNumber formats an integer or float field. format is the `format` tag
+// of the field:
+//
+//	fixed=N  floats with N digits after the decimal point
+//	sci      floats in scientific notation, with as many digits as needed
+//	sci=N    floats in scientific notation with N digits after the point
+//	hex      integers in hexadecimal with a 0x prefix
+//
+// Without a format, floats are written in the shortest form that reads back
+// as the same value, and NaN and infinities as NaN, +Inf and -Inf.
+func formatNumber(field reflect.Value, format string) (string, error) {
+	name, arg, hasArg := strings.Cut(format, "=")
+	prec := -1
+	if hasArg {
+		n, err := strconv.Atoi(arg)
+		if err != nil || n < 0 {
+			return "", fmt.Errorf("invalid precision in format '%s'", format)
+		}
+		prec = n
+	}
+
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		switch format {
+		case "":
+			return strconv.FormatInt(field.Int(), 10), nil
+		case "hex":
+			n := field.Int()
+			if n < 0 {
+				return "-0x" + strconv.FormatUint(uint64(-n), 16), nil
+			}
+			return "0x" + strconv.FormatInt(n, 16), nil
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		switch format {
+		case "":
+			return strconv.FormatUint(field.Uint(), 10), nil
+		case "hex":
+			return "0x" + strconv.FormatUint(field.Uint(), 16), nil
+		}
+	case reflect.Float32, reflect.Float64:
+		f := field.Float()
+		switch {
+		case math.IsNaN(f):
+			return "NaN", nil
+		case math.IsInf(f, 1):
+			return "+Inf", nil
+		case math.IsInf(f, -1):
+			return "-Inf", nil
+		}
+
+		bits := field.Type().Bits()
+		switch {
+		case format == "":
+			return strconv.FormatFloat(f, 'g', -1, bits), nil
+		case name == "fixed" && hasArg:
+			return strconv.FormatFloat(f, 'f', prec, bits), nil
+		case name == "sci":
+			return strconv.FormatFloat(f, 'e', prec, bits), nil
+		}
+	}
+
+	return "", fmt.Errorf("unsupported format '%s' for %s", format, field.Kind())
+}
+
+// intBase returns the base to parse an integer in: 0, which accepts the 0x
+// prefix, for hexadecimal values and 10 otherwise.
+func intBase(s string) int {
+	s = strings.TrimLeft(s, "+-")
+	if strings.HasPrefix(s, "0x") || strings.HasPrefix(s, "0X") {
+		return 0
+	}
+	return 10
+}
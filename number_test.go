@@ -0,0 +1,106 @@
+package dotprops
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+type NumericConfig struct {
+	Small   float64 `property:"small"`
+	Large   float64 `property:"large"`
+	Single  float32 `property:"single"`
+	NaN     float64 `property:"nan"`
+	Inf     float64 `property:"inf"`
+	NegInf  float64 `property:"neg.inf"`
+	Price   float64 `property:"price" format:"fixed=2"`
+	Sci     float64 `property:"sci" format:"sci=3"`
+	Mask    uint32  `property:"mask" format:"hex"`
+	Offset  *int    `property:"offset" format:"hex"`
+	Default int     `property:"default"`
+}
+
+func TestMarshalNumbers(t *testing.T) {
+	offset := -255
+	config := NumericConfig{
+		Small:   0.000001234,
+		Large:   1e21,
+		Single:  0.1,
+		NaN:     math.NaN(),
+		Inf:     math.Inf(1),
+		NegInf:  math.Inf(-1),
+		Price:   12.5,
+		Sci:     123456.789,
+		Mask:    0xff00,
+		Offset:  &offset,
+		Default: 42,
+	}
+
+	data, err := NewEncoder(WithKeyOrder(DeclaredKeys)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"small=1.234e-06",
+		"large=1e+21",
+		"single=0.1",
+		"nan=NaN",
+		"inf=+Inf",
+		"neg.inf=-Inf",
+		"price=12.50",
+		"sci=1.235e+05",
+		"mask=0xff00",
+		"offset=-0xff",
+		"default=42",
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded NumericConfig
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded.Small != config.Small || decoded.Large != config.Large || decoded.Single != config.Single {
+		t.Errorf("Expected floats to round trip, got %+v", decoded)
+	}
+	if !math.IsNaN(decoded.NaN) || !math.IsInf(decoded.Inf, 1) || !math.IsInf(decoded.NegInf, -1) {
+		t.Errorf("Expected NaN and infinities to round trip, got %+v", decoded)
+	}
+	if decoded.Mask != 0xff00 || decoded.Offset == nil || *decoded.Offset != -255 {
+		t.Errorf("Expected hex integers to round trip, got %+v", decoded)
+	}
+}
+
+func TestMarshalInvalidFormat(t *testing.T) {
+	tests := []interface{}{
+		struct {
+			N int `property:"n" format:"fixed=2"`
+		}{},
+		struct {
+			F float64 `property:"f" format:"hex"`
+		}{},
+		struct {
+			F float64 `property:"f" format:"fixed=x"`
+		}{},
+	}
+
+	for _, config := range tests {
+		if _, err := Marshal(config); err == nil {
+			t.Errorf("Expected Marshal of %T to fail due to an invalid format, but it did not", config)
+		}
+	}
+}
+
+func TestUnmarshalLeadingZeros(t *testing.T) {
+	var config struct {
+		N int `property:"n"`
+	}
+	if err := Unmarshal([]byte("n=010\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.N != 10 {
+		t.Errorf("Expected leading zeros to be decimal, got %d", config.N)
+	}
+}
@@ -0,0 +1,106 @@
+package dotprops
+
+import "sort"
+
+// KeyOrder selects the order of the keys in Marshal output.
+type KeyOrder int
+
+const (
+	// SortedKeys sorts keys lexically. This is the default.
+	SortedKeys KeyOrder = iota
+	// DeclaredKeys writes keys in the order their fields are declared, with
+	// the keys of a nested struct where the struct is declared.
+	DeclaredKeys
+	// NaturalKeys sorts keys lexically but compares runs of digits by their
+	// numeric value, so server.2 comes before server.10.
+	NaturalKeys
+)
+
+// WithKeyOrder sets the order of the keys in the output.
+func WithKeyOrder(order KeyOrder) EncoderOption {
+	return func(e *Encoder) {
+		e.order = order
+		e.less = nil
+	}
+}
+
+// WithKeyComparator sorts the keys in the output with less, which reports
+// whether key a comes before key b.
+func WithKeyComparator(less func(a, b string) bool) EncoderOption {
+	return func(e *Encoder) {
+		e.less = less
+	}
+}
+
+// sortKeys orders keys, given in declaration order, as configured.
+func (e *Encoder) sortKeys(keys []string) []string {
+	less := e.less
+	if less == nil {
+		switch e.order {
+		case DeclaredKeys:
+			return keys
+		case NaturalKeys:
+			less = naturalLess
+		default:
+			sort.Strings(keys)
+			return keys
+		}
+	}
+	sort.SliceStable(keys, func(i, j int) bool {
+		return less(keys[i], keys[j])
+	})
+	return keys
+}
+
+// naturalLess compares a and b lexically, except that runs of digits are
+// compared by their numeric value. Runs of equal value with different numbers
+// of leading zeros are ordered shortest first.
+func naturalLess(a, b string) bool {
+	for a != "" && b != "" {
+		if isDigit(a[0]) && isDigit(b[0]) {
+			na, restA := digitRun(a)
+			nb, restB := digitRun(b)
+
+			// Compare the values without leading zeros by length, then digits
+			va, vb := trimZeros(na), trimZeros(nb)
+			if len(va) != len(vb) {
+				return len(va) < len(vb)
+			}
+			if va != vb {
+				return va < vb
+			}
+			if len(na) != len(nb) {
+				return len(na) < len(nb)
+			}
+			a, b = restA, restB
+			continue
+		}
+		if a[0] != b[0] {
+			return a[0] < b[0]
+		}
+		a, b = a[1:], b[1:]
+	}
+	return len(a) < len(b)
+}
+
+// isDigit reports whether c is an ASCII digit.
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// digitRun splits s after its leading run of digits.
+func digitRun(s string) (string, string) {
+	i := 0
+	for i < len(s) && isDigit(s[i]) {
+		i++
+	}
+	return s[:i], s[i:]
+}
+
+// trimZeros removes the leading zeros of a run of digits.
+func trimZeros(digits string) string {
+	for len(digits) > 1 && digits[0] == '0' {
+		digits = digits[1:]
+	}
+	return digits
+}
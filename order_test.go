@@ -0,0 +1,68 @@
+package dotprops
+
+import (
+	"sort"
+	"strings"
+	"testing"
+)
+
+type OrderedConfig struct {
+	Name    string `property:"name"`
+	Servers struct {
+		S10 string `property:"10"`
+		S2  string `property:"2"`
+		S1  string `property:"1"`
+	} `property:"server"`
+	Debug bool `property:"debug"`
+}
+
+func orderedConfig() OrderedConfig {
+	var config OrderedConfig
+	config.Name = "app"
+	config.Servers.S10 = "j"
+	config.Servers.S2 = "b"
+	config.Servers.S1 = "a"
+	return config
+}
+
+func TestMarshalKeyOrder(t *testing.T) {
+	tests := []struct {
+		name     string
+		opts     []EncoderOption
+		expected []string
+	}{
+		{"default", nil, []string{"debug", "name", "server.1", "server.10", "server.2"}},
+		{"sorted", []EncoderOption{WithKeyOrder(SortedKeys)}, []string{"debug", "name", "server.1", "server.10", "server.2"}},
+		{"declared", []EncoderOption{WithKeyOrder(DeclaredKeys)}, []string{"name", "server.10", "server.2", "server.1", "debug"}},
+		{"natural", []EncoderOption{WithKeyOrder(NaturalKeys)}, []string{"debug", "name", "server.1", "server.2", "server.10"}},
+		{"comparator", []EncoderOption{WithKeyComparator(func(a, b string) bool { return a > b })}, []string{"server.2", "server.10", "server.1", "name", "debug"}},
+	}
+
+	for _, tt := range tests {
+		data, err := NewEncoder(tt.opts...).Marshal(orderedConfig())
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", tt.name, err)
+		}
+
+		var keys []string
+		for _, line := range strings.Split(strings.TrimSpace(string(data)), "\n") {
+			key, _, _ := strings.Cut(line, "=")
+			keys = append(keys, key)
+		}
+		if strings.Join(keys, ",") != strings.Join(tt.expected, ",") {
+			t.Errorf("%s: Expected keys %v, got %v", tt.name, tt.expected, keys)
+		}
+	}
+}
+
+func TestNaturalLess(t *testing.T) {
+	keys := []string{"a10", "a2", "a02", "a1b", "a1", "b", "a", "x.10.y", "x.9.z", "a001"}
+	sort.Slice(keys, func(i, j int) bool {
+		return naturalLess(keys[i], keys[j])
+	})
+
+	expected := "a,a1,a1b,a001,a2,a02,a10,b,x.9.z,x.10.y"
+	if got := strings.Join(keys, ","); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
@@ -0,0 +1,239 @@
+package dotprops
+
+import (
+	"reflect"
+	"strings"
+)
+
+// headerTimeFormat is the format of the timestamp line written by
+// WithTimestamp, the same as java.util.Properties.store.
+const headerTimeFormat = "Mon Jan 02 15:04:05 MST 2006"
+
+// WithHeader writes comment lines at the top of the output. Each line of
+// comments becomes a # line.
+func WithHeader(comments string) EncoderOption {
+	return func(e *Encoder) {
+		e.header = strings.Split(comments, "\n")
+	}
+}
+
+// WithTimestamp writes the current time as a # line at the top of the
+// output, after any header.
+func WithTimestamp() EncoderOption {
+	return func(e *Encoder) {
+		e.timestamp = true
+	}
+}
+
+// WithSectionSpacing separates top-level sections, the groups of keys that
+// share their first segment, with a blank line.
+func WithSectionSpacing() EncoderOption {
+	return func(e *Encoder) {
+		e.sections = true
+	}
+}
+
+// WithSeparator sets the separator written between keys and values, such as
+// " = " or ": ". The default is "=". Unmarshal reads "=" separators with any
+// surrounding spaces, and ":" separators WithDecodeSyntax(JavaSyntax).
+func WithSeparator(separator string) EncoderOption {
+	return func(e *Encoder) {
+		e.separator = separator
+	}
+}
+
+// WithAlignedValues pads keys so that the values of consecutive lines start in
+// the same column.
+func WithAlignedValues() EncoderOption {
+	return func(e *Encoder) {
+		e.align = true
+	}
+}
+
+// WithLineEnding sets the line ending, such as "\r\n". The default is "\n".
+func WithLineEnding(ending string) EncoderOption {
+	return func(e *Encoder) {
+		e.lineEnding = ending
+	}
+}
+
+// WithoutTrailingNewline leaves out the line ending after the last line.
+func WithoutTrailingNewline() EncoderOption {
+	return func(e *Encoder) {
+		e.noTrailingNewline = true
+	}
+}
+
+// WithMaxLineWidth continues values on further lines, ending each line but
+// the last in a backslash, so that lines stay within width bytes where
+// possible. Continuation lines are only read WithDecodeSyntax(JavaSyntax), so
+// use it together with WithEncodeSyntax(JavaSyntax).
+func WithMaxLineWidth(width int) EncoderOption {
+	return func(e *Encoder) {
+		e.maxWidth = width
+	}
+}
+
+// fieldComment returns the `comment` tag of a struct field, or its `doc` tag.
+func fieldComment(field reflect.StructField) string {
+	if comment, ok := field.Tag.Lookup("comment"); ok {
+		return comment
+	}
+	return field.Tag.Get("doc")
+}
+
+// outputLine is a line of Marshal output: a property, a comment or, if both
+// are empty, a blank line.
+type outputLine struct {
+	key, value string
+	comment    *string
+}
+
+// write formats the encoded properties in the order of keys. The comment of
+// a key, or of a struct a key belongs to, is written above its first key.
+// Commented keys are written as comments themselves.
+func (e *Encoder) write(keys []string, props *Properties, comments map[string]string, commented map[string]bool) []byte {
+	var lines []outputLine
+	addComment := func(text string) {
+		lines = append(lines, outputLine{comment: &text})
+	}
+
+	for _, line := range e.header {
+		addComment(line)
+	}
+	if e.timestamp {
+		addComment(e.now().Format(headerTimeFormat))
+	}
+
+	written := make(map[string]bool)
+	section := ""
+	for i, key := range keys {
+		if e.sections {
+			first, _, _ := strings.Cut(key, ".")
+			if i > 0 && first != section {
+				lines = append(lines, outputLine{})
+			}
+			section = first
+		}
+
+		// Write the comments of the enclosing structs, outermost first
+		for _, prefix := range keyPrefixes(key) {
+			if comment, ok := comments[prefix]; ok && !written[prefix] {
+				for _, line := range strings.Split(comment, "\n") {
+					addComment(line)
+				}
+				written[prefix] = true
+			}
+		}
+
+		value, _ := props.Get(key)
+		if commented[key] {
+			addComment(strings.TrimRight(e.syntax.escapeKey(key)+e.separator+e.syntax.escapeValue(value), " "))
+			continue
+		}
+		lines = append(lines, outputLine{key: e.syntax.escapeKey(key), value: e.syntax.escapeValue(value)})
+	}
+
+	return e.format(lines)
+}
+
+// format renders lines with the configured separator, alignment, width and
+// line ending. Values are aligned within each block of lines between blank
+// lines.
+func (e *Encoder) format(lines []outputLine) []byte {
+	var sb strings.Builder
+
+	width := 0
+	for i, line := range lines {
+		if e.align && i > 0 && lines[i-1].key == "" && lines[i-1].comment == nil {
+			width = 0
+		}
+		if e.align && width == 0 {
+			width = blockKeyWidth(lines[i:])
+		}
+
+		switch {
+		case line.comment != nil:
+			if *line.comment == "" {
+				sb.WriteString("#")
+			} else {
+				sb.WriteString("# " + *line.comment)
+			}
+		case line.key != "":
+			key := line.key
+			if e.align {
+				key += strings.Repeat(" ", width-len(key))
+			}
+			e.writeProperty(&sb, key+e.separator, line.value)
+		}
+
+		if i < len(lines)-1 || !e.noTrailingNewline {
+			sb.WriteString(e.lineEnding)
+		}
+	}
+
+	return []byte(sb.String())
+}
+
+// blockKeyWidth returns the length of the longest key before the first blank
+// line.
+func blockKeyWidth(lines []outputLine) int {
+	width := 0
+	for _, line := range lines {
+		if line.key == "" && line.comment == nil {
+			break
+		}
+		if len(line.key) > width {
+			width = len(line.key)
+		}
+	}
+	return width
+}
+
+// continuationIndent starts the continuation lines of long values.
+const continuationIndent = "    "
+
+// writeProperty writes key and value, continuing the value on further lines
+// ending in a backslash where it would exceed the maximum line width. Lines are
+// not broken before whitespace, which the parser would drop, or after a
+// backslash, which would escape the continuation.
+func (e *Encoder) writeProperty(sb *strings.Builder, key, value string) {
+	line := key
+	for e.maxWidth > 0 && len(line)+len(value) > e.maxWidth {
+		cut := e.maxWidth - len(line) - 1
+		for cut > 0 && !canBreak(value, cut) {
+			cut--
+		}
+		if cut <= 0 {
+			// Nowhere to break within the width; break at the first
+			// possible point instead
+			cut = 1
+			for cut < len(value) && !canBreak(value, cut) {
+				cut++
+			}
+			if cut >= len(value) {
+				break
+			}
+		}
+		sb.WriteString(line + value[:cut] + "\\" + e.lineEnding)
+		line, value = continuationIndent, value[cut:]
+	}
+	sb.WriteString(line + value)
+}
+
+// canBreak reports whether value can be continued on a new line at i.
+func canBreak(value string, i int) bool {
+	return i < len(value) && value[i] != ' ' && value[i] != '\t' && value[i-1] != '\\'
+}
+
+// keyPrefixes returns every prefix of key at a dot boundary, shortest
+// first, followed by key itself.
+func keyPrefixes(key string) []string {
+	var prefixes []string
+	for i := 0; i < len(key); i++ {
+		if key[i] == '.' {
+			prefixes = append(prefixes, key[:i])
+		}
+	}
+	return append(prefixes, key)
+}
@@ -0,0 +1,282 @@
+package dotprops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type DocumentedConfig struct {
+	AppName  string `property:"app.name" comment:"Name shown in the UI"`
+	Port     int    `property:"app.port" doc:"Port to listen on.\nUse 0 for a random port."`
+	Database struct {
+		Host string `property:"host"`
+		Port int    `property:"port" comment:"Defaults to 5432"`
+	} `property:"database" comment:"Connection settings"`
+	Debug bool `property:"debug"`
+}
+
+func documentedConfig() DocumentedConfig {
+	var config DocumentedConfig
+	config.AppName = "MyApp"
+	config.Port = 8080
+	config.Database.Host = "localhost"
+	config.Database.Port = 5432
+	return config
+}
+
+func TestMarshalWithComments(t *testing.T) {
+	data, err := Marshal(documentedConfig())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := `# Name shown in the UI
+app.name=MyApp
+# Port to listen on.
+# Use 0 for a random port.
+app.port=8080
+# Connection settings
+database.host=localhost
+# Defaults to 5432
+database.port=5432
+debug=false
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestMarshalWithHeaderAndSections(t *testing.T) {
+	enc := NewEncoder(WithHeader("Generated file\n\nDo not edit"), WithTimestamp(), WithSectionSpacing())
+	enc.now = func() time.Time {
+		return time.Date(2024, time.March, 5, 14, 7, 9, 0, time.UTC)
+	}
+
+	data, err := enc.Marshal(documentedConfig())
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := `# Generated file
+#
+# Do not edit
+# Tue Mar 05 14:07:09 UTC 2024
+# Name shown in the UI
+app.name=MyApp
+# Port to listen on.
+# Use 0 for a random port.
+app.port=8080
+
+# Connection settings
+database.host=localhost
+# Defaults to 5432
+database.port=5432
+
+debug=false
+`
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	// Round trip ignores the comments
+	var decoded DocumentedConfig
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded != documentedConfig() {
+		t.Errorf("Expected %+v, got %+v", documentedConfig(), decoded)
+	}
+}
+
+func TestRedactedSkipsComments(t *testing.T) {
+	expected := `app.name=MyApp app.port=8080 database.host=localhost database.port=5432 debug=false`
+	if got := Redacted(documentedConfig()); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+}
+
+type FormattedConfig struct {
+	Name        string `property:"name"`
+	Description string `property:"app.description"`
+	Path        string `property:"app.path"`
+}
+
+func TestMarshalFormatting(t *testing.T) {
+	config := FormattedConfig{Name: "demo", Description: "a short one", Path: `C:\dir`}
+
+	tests := []struct {
+		name     string
+		opts     []EncoderOption
+		expected string
+	}{
+		{
+			"separator",
+			[]EncoderOption{WithSeparator(" = ")},
+			"app.description = a short one\napp.path = C:\\dir\nname = demo\n",
+		},
+		{
+			"aligned",
+			[]EncoderOption{WithSeparator(": "), WithAlignedValues(), WithSectionSpacing()},
+			"app.description: a short one\napp.path       : C:\\dir\n\nname: demo\n",
+		},
+		{
+			"crlf",
+			[]EncoderOption{WithLineEnding("\r\n"), WithHeader("header")},
+			"# header\r\napp.description=a short one\r\napp.path=C:\\dir\r\nname=demo\r\n",
+		},
+		{
+			"no trailing newline",
+			[]EncoderOption{WithoutTrailingNewline()},
+			"app.description=a short one\napp.path=C:\\dir\nname=demo",
+		},
+	}
+
+	for _, tt := range tests {
+		data, err := NewEncoder(tt.opts...).Marshal(config)
+		if err != nil {
+			t.Fatalf("%s: Marshal failed: %v", tt.name, err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("%s: Expected:\n%q\nGot:\n%q", tt.name, tt.expected, data)
+		}
+
+		var decoded FormattedConfig
+		if err := NewDecoder(WithDecodeSyntax(JavaSyntax)).Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("%s: Unmarshal failed: %v", tt.name, err)
+		}
+		if decoded != config {
+			t.Errorf("%s: Expected round trip to give %+v, got %+v", tt.name, config, decoded)
+		}
+	}
+}
+
+func TestMarshalMaxLineWidth(t *testing.T) {
+	config := FormattedConfig{
+		Name:        "demo",
+		Description: "a rather long description that does not fit",
+		Path:        `C:\some\very\long\path\to\a\file`,
+	}
+
+	data, err := NewEncoder(WithMaxLineWidth(24), WithEncodeSyntax(JavaSyntax)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(string(data), "\n"), "\n") {
+		if len(line) > 24 {
+			t.Errorf("Expected lines of at most 24 bytes, got %q", line)
+		}
+	}
+	if !strings.Contains(string(data), "\\\n    ") {
+		t.Errorf("Expected continuation lines, got:\n%s", data)
+	}
+
+	var decoded FormattedConfig
+	if err := NewDecoder(WithDecodeSyntax(JavaSyntax)).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if decoded != config {
+		t.Errorf("Expected round trip to give %+v, got %+v\n%s", config, decoded, data)
+	}
+}
+
+func TestMarshalTrailingBackslash(t *testing.T) {
+	type Probe struct {
+		Dir  string `property:"dir"`
+		Name string `property:"name"`
+	}
+	config := Probe{Dir: `C:\temp\`, Name: "x"}
+
+	tests := []struct {
+		syntax   Syntax
+		expected string
+	}{
+		{PlainSyntax, "dir=C:\\temp\\\nname=x\n"},
+		{JavaSyntax, "dir=C:\\temp\\\\\nname=x\n"},
+	}
+
+	for _, tt := range tests {
+		data, err := NewEncoder(WithEncodeSyntax(tt.syntax)).Marshal(config)
+		if err != nil {
+			t.Fatalf("Marshal failed: %v", err)
+		}
+		if string(data) != tt.expected {
+			t.Errorf("Expected:\n%q\nGot:\n%q", tt.expected, data)
+		}
+
+		dec := NewDecoder(WithDecodeSyntax(tt.syntax))
+		var decoded Probe
+		if err := dec.Unmarshal(data, &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded != config {
+			t.Errorf("Expected round trip to give %+v, got %+v", config, decoded)
+		}
+
+		// Documents escape values for their own syntax
+		doc, err := tt.syntax.ParseDocument([]byte("dir=x\nname=x\n"))
+		if err != nil {
+			t.Fatalf("ParseDocument failed: %v", err)
+		}
+		doc.Set("dir", `C:\temp\`)
+		if err := dec.Unmarshal(doc.Bytes(), &decoded); err != nil {
+			t.Fatalf("Unmarshal failed: %v", err)
+		}
+		if decoded != config {
+			t.Errorf("Expected the document to give %+v, got %+v", config, decoded)
+		}
+	}
+
+	// Property sets are written as they are
+	p := NewProperties()
+	p.Set("dir", `a\\b\`)
+	parsed, err := Parse(p.Bytes())
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if value, _ := parsed.Get("dir"); value != `a\\b\` {
+		t.Errorf("Expected dir to be %q, got %q", `a\\b\`, value)
+	}
+}
+
+func TestMarshalSeparatorsInKeys(t *testing.T) {
+	type Routes struct {
+		Targets map[string]string `property:"routes"`
+	}
+	config := Routes{Targets: map[string]string{"a:b": "v", "x=y": `z\:`}}
+
+	data, err := NewEncoder(WithEncodeSyntax(JavaSyntax)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "routes.a\\:b=v\nroutes.x\\=y=z\\\\:\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%q\nGot:\n%q", expected, data)
+	}
+
+	var decoded Routes
+	if err := NewDecoder(WithDecodeSyntax(JavaSyntax)).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected round trip to give %+v, got %+v", config, decoded)
+	}
+
+	// An unescaped ":" separates the key from the value in JavaSyntax only
+	p, err := JavaSyntax.Parse([]byte("a:b=v\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if value, _ := p.Get("a"); value != "b=v" {
+		t.Errorf("Expected a to be 'b=v', got %v", p.Keys())
+	}
+	p, err = Parse([]byte("a:b=v\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if value, _ := p.Get("a:b"); value != "v" {
+		t.Errorf("Expected a:b to be 'v', got %v", p.Keys())
+	}
+}
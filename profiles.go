@@ -0,0 +1,216 @@
+package dotprops
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"strings"
+	"unicode"
+)
+
+// profileKey is the key that restricts a document to a set of profiles.
+const profileKey = "spring.config.activate.on-profile"
+
+// Profiles returns a source that layers Spring-style profile files: the base
+// file base.properties followed by base-{profile}.properties for every
+// profile in order, so later profiles take precedence. base may be given with
+// or without the .properties extension. The base file is required; missing
+// profile files are skipped.
+//
+// Every file may contain several documents separated by "#---" lines. A
+// document that sets spring.config.activate.on-profile is only applied when
+// its profile expression matches the active profiles.
+func Profiles(base string, profiles ...string) Source {
+	files := &fileReader{profiles: profiles, evaluateProfiles: true}
+	return &profileSource{base: base, profiles: profiles, files: files}
+}
+
+// ProfilesFS is like Profiles but reads the files from fsys.
+func ProfilesFS(fsys fs.FS, base string, profiles ...string) Source {
+	files := &fileReader{fsys: fsys, profiles: profiles, evaluateProfiles: true}
+	return &profileSource{base: base, profiles: profiles, files: files}
+}
+
+type profileSource struct {
+	base     string
+	profiles []string
+	files    *fileReader
+}
+
+func (s *profileSource) Name() string { return s.base }
+
+func (s *profileSource) Load() (*Properties, error) {
+	return s.loadSyntax(PlainSyntax)
+}
+
+func (s *profileSource) loadSyntax(syntax Syntax) (*Properties, error) {
+	base := strings.TrimSuffix(s.base, ".properties")
+	files := s.files.withSyntax(syntax)
+
+	p, err := files.load(base + ".properties")
+	if err != nil {
+		return nil, err
+	}
+
+	for _, profile := range s.profiles {
+		layer, err := files.load(base + "-" + profile + ".properties")
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		p.Merge(layer)
+	}
+
+	return p, nil
+}
+
+// mergeDocuments merges the documents that are active for the given
+// profiles.
+func mergeDocuments(docs []*Properties, profiles []string) (*Properties, error) {
+	p := NewProperties()
+	for _, doc := range docs {
+		if expr, ok := doc.Get(profileKey); ok {
+			active, err := matchProfiles(expr, profiles)
+			if err != nil {
+				origin, _ := doc.Origin(profileKey)
+				return nil, fmt.Errorf("invalid profile expression at %s: %v", origin, err)
+			}
+			if !active {
+				continue
+			}
+			doc.Delete(profileKey)
+		}
+		p.Merge(doc)
+	}
+
+	return p, nil
+}
+
+// matchProfiles reports whether a profile expression matches the active
+// profiles. Expressions are profile names combined with "!", "&", "|" and
+// parentheses; a comma-separated list matches if any element does.
+func matchProfiles(expr string, active []string) (bool, error) {
+	e := &profileExpr{tokens: tokenizeProfiles(expr), active: active}
+	if len(e.tokens) == 0 {
+		return false, errors.New("empty expression")
+	}
+	match, err := e.parseOr()
+	if err != nil {
+		return false, err
+	}
+	if e.pos < len(e.tokens) {
+		return false, fmt.Errorf("unexpected %q in %q", e.tokens[e.pos], expr)
+	}
+	return match, nil
+}
+
+// tokenizeProfiles splits a profile expression into names and operators.
+func tokenizeProfiles(expr string) []string {
+	var tokens []string
+	var name strings.Builder
+	flush := func() {
+		if name.Len() > 0 {
+			tokens = append(tokens, name.String())
+			name.Reset()
+		}
+	}
+
+	for _, r := range expr {
+		switch {
+		case strings.ContainsRune("!&|(),", r):
+			flush()
+			tokens = append(tokens, string(r))
+		case unicode.IsSpace(r):
+			flush()
+		default:
+			name.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// profileExpr is a recursive descent parser for profile expressions.
+type profileExpr struct {
+	tokens []string
+	pos    int
+	active []string
+}
+
+func (e *profileExpr) peek() string {
+	if e.pos < len(e.tokens) {
+		return e.tokens[e.pos]
+	}
+	return ""
+}
+
+// parseOr parses terms separated by "|" or ",".
+func (e *profileExpr) parseOr() (bool, error) {
+	match, err := e.parseAnd()
+	if err != nil {
+		return false, err
+	}
+	for e.peek() == "|" || e.peek() == "," {
+		e.pos++
+		next, err := e.parseAnd()
+		if err != nil {
+			return false, err
+		}
+		match = match || next
+	}
+	return match, nil
+}
+
+// parseAnd parses terms separated by "&".
+func (e *profileExpr) parseAnd() (bool, error) {
+	match, err := e.parseUnary()
+	if err != nil {
+		return false, err
+	}
+	for e.peek() == "&" {
+		e.pos++
+		next, err := e.parseUnary()
+		if err != nil {
+			return false, err
+		}
+		match = match && next
+	}
+	return match, nil
+}
+
+// parseUnary parses a negation, a parenthesized expression or a profile name.
+func (e *profileExpr) parseUnary() (bool, error) {
+	token := e.peek()
+	switch token {
+	case "":
+		return false, errors.New("unexpected end of expression")
+	case "!":
+		e.pos++
+		match, err := e.parseUnary()
+		return !match, err
+	case "(":
+		e.pos++
+		match, err := e.parseOr()
+		if err != nil {
+			return false, err
+		}
+		if e.peek() != ")" {
+			return false, errors.New("missing closing parenthesis")
+		}
+		e.pos++
+		return match, nil
+	case "&", "|", ",", ")":
+		return false, fmt.Errorf("unexpected %q", token)
+	}
+
+	e.pos++
+	for _, profile := range e.active {
+		if profile == token {
+			return true, nil
+		}
+	}
+	return false, nil
+}
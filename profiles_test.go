@@ -0,0 +1,128 @@
+package dotprops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+)
+
+func TestProfilesLayering(t *testing.T) {
+	fsys := fstest.MapFS{
+		"config/application.properties": {Data: []byte(`
+app.name=BaseApp
+app.port=8080
+app.debug=false
+`)},
+		"config/application-dev.properties":   {Data: []byte("app.debug=true\n")},
+		"config/application-local.properties": {Data: []byte("app.port=3000\n")},
+	}
+
+	var config SimpleConfig
+	props, err := NewLoader(ProfilesFS(fsys, "config/application", "dev", "local", "missing")).Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.AppName != "BaseApp" {
+		t.Errorf("Expected AppName 'BaseApp', got '%s'", config.AppName)
+	}
+	if config.Port != 3000 {
+		t.Errorf("Expected Port 3000, got %d", config.Port)
+	}
+	if config.Debug != true {
+		t.Errorf("Expected Debug true, got %v", config.Debug)
+	}
+
+	origin, _ := props.Origin("app.port")
+	if origin.String() != "config/application-local.properties:1" {
+		t.Errorf("Expected origin 'config/application-local.properties:1', got '%s'", origin)
+	}
+}
+
+func TestProfilesMultiDocument(t *testing.T) {
+	dir := t.TempDir()
+	data := []byte(`
+app.name=BaseApp
+app.port=8080
+#---
+spring.config.activate.on-profile=prod
+app.port=80
+#---
+spring.config.activate.on-profile=dev | test
+app.port=3000
+app.debug=true
+#---
+spring.config.activate.on-profile=!prod
+app.name=NonProdApp
+`)
+	if err := os.WriteFile(filepath.Join(dir, "application.properties"), data, 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		profiles []string
+		name     string
+		port     int
+		debug    bool
+	}{
+		{nil, "NonProdApp", 8080, false},
+		{[]string{"prod"}, "BaseApp", 80, false},
+		{[]string{"test"}, "NonProdApp", 3000, true},
+	}
+
+	for _, tt := range tests {
+		var config SimpleConfig
+		props, err := NewLoader(Profiles(filepath.Join(dir, "application.properties"), tt.profiles...)).Load(&config)
+		if err != nil {
+			t.Fatalf("Load with profiles %v failed: %v", tt.profiles, err)
+		}
+		if config.AppName != tt.name || config.Port != tt.port || config.Debug != tt.debug {
+			t.Errorf("Profiles %v: expected %s/%d/%v, got %+v", tt.profiles, tt.name, tt.port, tt.debug, config)
+		}
+		if _, ok := props.Get(profileKey); ok {
+			t.Errorf("Profiles %v: expected %s to be removed", tt.profiles, profileKey)
+		}
+	}
+}
+
+func TestProfilesMissingBase(t *testing.T) {
+	_, err := ProfilesFS(fstest.MapFS{}, "application", "dev").Load()
+	if err == nil {
+		t.Fatal("Expected Load to fail for a missing base file, but it did not")
+	}
+}
+
+func TestMatchProfiles(t *testing.T) {
+	active := []string{"prod", "cloud"}
+	tests := []struct {
+		expr  string
+		match bool
+	}{
+		{"prod", true},
+		{"dev", false},
+		{"!dev", true},
+		{"prod & cloud", true},
+		{"prod & !cloud", false},
+		{"dev | cloud", true},
+		{"dev, prod", true},
+		{"(dev | prod) & !local", true},
+	}
+
+	for _, tt := range tests {
+		match, err := matchProfiles(tt.expr, active)
+		if err != nil {
+			t.Errorf("matchProfiles(%q) failed: %v", tt.expr, err)
+			continue
+		}
+		if match != tt.match {
+			t.Errorf("matchProfiles(%q) = %v, expected %v", tt.expr, match, tt.match)
+		}
+	}
+
+	for _, expr := range []string{"", "prod &", "(prod", "prod)", "& prod"} {
+		if _, err := matchProfiles(expr, active); err == nil {
+			t.Errorf("Expected matchProfiles(%q) to fail, but it did not", expr)
+		}
+	}
+}
@@ -1,68 +1,274 @@
 package dotprops
 
 import (
-	"bufio"
 	"fmt"
 	"reflect"
-	"regexp"
 	"strconv"
 	"strings"
 )
 
-// parseProperties reads properties data and returns a map of key-value pairs.
-func parseProperties(data []byte) (map[string]interface{}, error) {
-	props := make(map[string]interface{})
-	scanner := bufio.NewScanner(strings.NewReader(string(data)))
-	pattern := regexp.MustCompile("^([^#][^=]*)=(.*)")
+// Properties is an ordered set of flat key/value pairs. Keys keep the position
+// of their first insertion; setting an existing key replaces its value and
+// records the old one as shadowed.
+type Properties struct {
+	keys      []string
+	entries   map[string]*entry
+	sensitive map[string]bool
+}
+
+// entry is the current value of a key together with its provenance.
+type entry struct {
+	value    string
+	origin   Origin
+	shadowed []Value // most recently shadowed first
+	// fromTag is set for values of `default` tags, which any other value
+	// overrides.
+	fromTag bool
+}
+
+// NewProperties returns an empty property set.
+func NewProperties() *Properties {
+	return &Properties{entries: make(map[string]*entry), sensitive: make(map[string]bool)}
+}
+
+// Parse reads properties data in PlainSyntax into a flat, ordered property
+// set. Use JavaSyntax.Parse for files that use ":" separators, continuation
+// lines or escapes.
+func Parse(data []byte) (*Properties, error) {
+	return PlainSyntax.Parse(data)
+}
+
+// parse reads properties data in the given syntax, recording source and line
+// number as the origin of every value.
+func parse(source string, data []byte, syntax Syntax) (*Properties, error) {
+	docs, err := parseDocuments(source, data, syntax, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	// Without profile evaluation, document separators are plain comments
+	p := NewProperties()
+	for _, doc := range docs {
+		p.Merge(doc)
+	}
+	return p, nil
+}
+
+// includeFunc resolves an include directive for the named file and returns
+// the properties to merge in its place.
+type includeFunc func(name string, optional bool) (*Properties, error)
+
+// parseDocuments reads properties data that may contain several documents
+// separated by "#---" or "!---" lines, returning one property set per
+// document. If include is not nil, "include" and "includeoptional" keys are
+// resolved through it instead of being stored.
+func parseDocuments(source string, data []byte, syntax Syntax, include includeFunc) ([]*Properties, error) {
+	p := NewProperties()
+	docs := []*Properties{p}
+	physical := strings.Split(strings.ReplaceAll(string(data), "\r\n", "\n"), "\n")
+
+	for i := 0; i < len(physical); i++ {
+		start := i + 1
+		var line string
+		line, i = syntax.joinLines(physical, i)
 
-	for scanner.Scan() {
-		line := scanner.Text()
-		line = strings.TrimSpace(line)
+		// Start a new document at a separator
+		if line == "#---" || line == "!---" {
+			p = NewProperties()
+			docs = append(docs, p)
+			continue
+		}
 
 		// Skip empty lines and comments
-		if len(line) == 0 || strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!") {
+		if len(line) == 0 || isComment(line) {
 			continue
 		}
 
-		match := pattern.FindStringSubmatch(line)
+		match := syntax.match(line)
 		if len(match) > 0 {
-			key := strings.TrimSpace(match[1])
-			value := strings.TrimSpace(match[2])
-
-			// Split the key into parts for nested maps
-			keyList := strings.Split(key, ".")
+			origin := Origin{Source: source, Line: start}
+			key := syntax.unescape(strings.TrimSpace(match[1]))
+			value := syntax.unescape(strings.TrimSpace(match[2]))
 
-			current := props
-			for i := 0; i < len(keyList)-1; i++ {
-				k := keyList[i]
-				if _, ok := current[k]; !ok {
-					current[k] = make(map[string]interface{})
-				}
-				// Type assertion to navigate deeper into the nested map
-				if nextMap, ok := current[k].(map[string]interface{}); ok {
-					current = nextMap
-				} else {
-					// Handle type mismatch if the existing key is not a map
-					return nil, fmt.Errorf("type mismatch at key: %s", k)
+			// Merge included files in place of the directive. Errors are not
+			// wrapped so that a missing nested include is not mistaken for a
+			// missing top-level file.
+			if include != nil && (key == "include" || key == "includeoptional") {
+				for _, name := range strings.Split(value, ",") {
+					name = strings.TrimSpace(name)
+					if name == "" {
+						continue
+					}
+					included, err := include(name, key == "includeoptional")
+					if err != nil {
+						return nil, fmt.Errorf("%s: %s %s: %v", origin, key, name, err)
+					}
+					p.Merge(included)
 				}
+				continue
 			}
 
-			// Assign the value to the last key
-			lastKey := keyList[len(keyList)-1]
-			current[lastKey] = value
-		} else {
-			// Line didn't match the pattern, handle as needed (skip or log)
-			continue
+			p.set(key, value, origin)
 		}
+		// Lines that don't match the pattern are skipped
 	}
 
-	if err := scanner.Err(); err != nil {
-		return nil, err
+	return docs, nil
+}
+
+// isComment reports whether a trimmed line is a comment.
+func isComment(line string) bool {
+	return strings.HasPrefix(line, "#") || strings.HasPrefix(line, "!")
+}
+
+// Get returns the value stored for key.
+func (p *Properties) Get(key string) (string, bool) {
+	e, ok := p.entries[key]
+	if !ok {
+		return "", false
+	}
+	return e.value, true
+}
+
+// Set stores value under key, replacing any previous value.
+func (p *Properties) Set(key, value string) {
+	p.set(key, value, Origin{})
+}
+
+// set stores value under key with the given origin.
+func (p *Properties) set(key, value string, origin Origin) {
+	p.put(key, &entry{value: value, origin: origin})
+}
+
+// put stores e under key. A previous entry for key, and everything it
+// shadowed, becomes shadowed by e.
+func (p *Properties) put(key string, e *entry) {
+	if old, ok := p.entries[key]; ok {
+		shadowed := make([]Value, 0, len(e.shadowed)+1+len(old.shadowed))
+		shadowed = append(shadowed, e.shadowed...)
+		shadowed = append(shadowed, Value{Text: old.value, Origin: old.origin})
+		e.shadowed = append(shadowed, old.shadowed...)
+	} else {
+		p.keys = append(p.keys, key)
+	}
+	p.entries[key] = e
+}
+
+// Delete removes key from the set.
+func (p *Properties) Delete(key string) {
+	if _, ok := p.entries[key]; !ok {
+		return
+	}
+	delete(p.entries, key)
+	for i, k := range p.keys {
+		if k == key {
+			p.keys = append(p.keys[:i], p.keys[i+1:]...)
+			break
+		}
 	}
+}
 
+// Keys returns the keys in insertion order.
+func (p *Properties) Keys() []string {
+	keys := make([]string, len(p.keys))
+	copy(keys, p.keys)
+	return keys
+}
+
+// Len returns the number of keys in the set.
+func (p *Properties) Len() int {
+	return len(p.keys)
+}
+
+// Bytes returns the properties in insertion order as key=value lines.
+func (p *Properties) Bytes() []byte {
+	var sb strings.Builder
+	for _, key := range p.keys {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", key, p.entries[key].value))
+	}
+	return []byte(sb.String())
+}
+
+// Merge copies every key of other into p. Values from other win and shadow
+// the values already in p.
+func (p *Properties) Merge(other *Properties) {
+	for _, key := range other.keys {
+		e := *other.entries[key]
+		e.shadowed = append([]Value(nil), e.shadowed...)
+		p.put(key, &e)
+		if other.sensitive[key] {
+			p.sensitive[key] = true
+		}
+	}
+}
+
+// toMap converts the flat set into the nested map used by setStructFields.
+func (p *Properties) toMap() (map[string]interface{}, error) {
+	props := make(map[string]interface{})
+	for _, key := range p.keys {
+		if err := setNestedProperty(props, key, p.entries[key].value); err != nil {
+			return nil, err
+		}
+	}
 	return props, nil
 }
 
+// parseProperties reads properties data and returns a map of key-value pairs.
+func parseProperties(data []byte) (map[string]interface{}, error) {
+	p, err := Parse(data)
+	if err != nil {
+		return nil, err
+	}
+	return p.toMap()
+}
+
+// setNestedProperty stores value in the nested map under a dot-separated key.
+func setNestedProperty(props map[string]interface{}, key string, value string) error {
+	// Split the key into parts for nested maps
+	keyList := strings.Split(key, ".")
+
+	current := props
+	for i := 0; i < len(keyList)-1; i++ {
+		k := keyList[i]
+		if _, ok := current[k]; !ok {
+			current[k] = make(map[string]interface{})
+		}
+		// Type assertion to navigate deeper into the nested map
+		if nextMap, ok := current[k].(map[string]interface{}); ok {
+			current = nextMap
+		} else {
+			// Handle type mismatch if the existing key is not a map
+			return fmt.Errorf("type mismatch at key: %s", k)
+		}
+	}
+
+	// Assign the value to the last key
+	lastKey := keyList[len(keyList)-1]
+	current[lastKey] = value
+	return nil
+}
+
+// propUnmarshallerType is the reflect.Type of the PropUnmarshaller interface.
+var propUnmarshallerType = reflect.TypeOf((*PropUnmarshaller)(nil)).Elem()
+
+// propsUnmarshallerType is the reflect.Type of the PropsUnmarshaller interface.
+var propsUnmarshallerType = reflect.TypeOf((*PropsUnmarshaller)(nil)).Elem()
+
+// decodesItself reports whether a pointer to t implements PropUnmarshaller or
+// PropsUnmarshaller, so that its fields are not decoded one by one.
+func decodesItself(t reflect.Type) bool {
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(propUnmarshallerType) || ptr.Implements(propsUnmarshallerType)
+}
+
+// joinKey appends key to a dot-separated prefix.
+func joinKey(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
 // getNestedProperty traverses the nested map to retrieve the value for a dot-separated key.
 func getNestedProperty(props map[string]interface{}, key string) (interface{}, bool) {
 	parts := strings.Split(key, ".")
@@ -82,16 +288,46 @@ func getNestedProperty(props map[string]interface{}, key string) (interface{}, b
 	return current, true
 }
 
+// decodeState holds the state of a single decode pass.
+type decodeState struct {
+	// props is the flat property set being decoded. It supplies the origin
+	// of values for error messages and may be nil.
+	props *Properties
+	// decoder is the Decoder of the pass, if any. RawProps fields keep it
+	// to decode their properties later.
+	decoder *Decoder
+	// interp resolves placeholders in values; nil disables interpolation.
+	interp *interpolator
+	// decrypter decrypts ENC(...) values; nil rejects them.
+	decrypter Decrypter
+	// decodeFuncs and hooks convert values before the built-in decoding.
+	decodeFuncs map[reflect.Type]DecodeFunc
+	hooks       []DecodeHook
+	// fallback enables the JSON decoding of values.
+	fallback Fallback
+	// emptyAbsent skips empty values and nulls zeroes fields set to Null.
+	emptyAbsent, nulls bool
+	// secret is set while decoding below a sensitive field.
+	secret bool
+}
+
 // setStructFields sets the fields of the struct based on the provided properties.
 func setStructFields(structVal reflect.Value, props map[string]interface{}) error {
+	d := &decodeState{}
+	return d.decodeStruct("", structVal, props)
+}
+
+// decodeStruct sets the fields of the struct based on the provided properties.
+// prefix is the full key under which props are nested.
+func (d *decodeState) decodeStruct(prefix string, structVal reflect.Value, props map[string]interface{}) error {
 	structType := structVal.Type()
 
 	for i := 0; i < structVal.NumField(); i++ {
 		field := structVal.Field(i)
 		fieldType := structType.Field(i)
 
-		// Skip unexported fields
-		if !field.CanSet() {
+		// Skip unexported fields and those tagged "-"
+		if !field.CanSet() || skipped(fieldType) {
 			continue
 		}
 
@@ -99,7 +335,7 @@ func setStructFields(structVal reflect.Value, props map[string]interface{}) erro
 		if fieldType.Anonymous {
 			// Handle embedded struct: pass the same props map
 			if field.Kind() == reflect.Struct {
-				err := setStructFields(field, props)
+				err := d.decodeStruct(prefix, field, props)
 				if err != nil {
 					return err
 				}
@@ -107,7 +343,7 @@ func setStructFields(structVal reflect.Value, props map[string]interface{}) erro
 				if field.IsNil() {
 					field.Set(reflect.New(field.Type().Elem()))
 				}
-				err := setStructFields(field.Elem(), props)
+				err := d.decodeStruct(prefix, field.Elem(), props)
 				if err != nil {
 					return err
 				}
@@ -116,26 +352,78 @@ func setStructFields(structVal reflect.Value, props map[string]interface{}) erro
 		}
 
 		// Get the property key from the struct tag or use the field name
-		propertyKey := fieldType.Tag.Get("property")
-		if propertyKey == "" {
-			propertyKey = fieldType.Name
-		}
+		propertyKey := propertyKey(fieldType)
+		fullKey := joinKey(prefix, propertyKey)
+		sensitive := d.secret || isSensitive(fieldType)
 
 		// Retrieve the value using the helper function
 		value, ok := getNestedProperty(props, propertyKey)
 		if !ok {
 			continue // Property not found in data
 		}
+		if value, ok = d.present(field, fullKey, value); !ok {
+			continue // Explicitly null, or empty and treated as absent
+		}
+		from := originSuffix(d.props, fullKey)
+
+		// Handle interface fields through the type registry
+		if field.Kind() == reflect.Interface {
+			subProps, ok := value.(map[string]interface{})
+			if !ok {
+				return fmt.Errorf("expected map for interface field '%s'%s, got %T", fullKey, from, value)
+			}
+			err := d.decodeInterface(field, fieldType, fullKey, subProps, sensitive)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Capture the sub-tree of RawProps fields as is
+		if isRawProps(field.Type()) {
+			err := d.decodeRaw(field, fullKey, value, sensitive)
+			if err != nil {
+				return err
+			}
+			continue
+		}
+
+		// Check if the field decodes a whole sub-tree itself
+		if elemType := indirectType(field.Type()); decodesSubtree(elemType) {
+			err := d.decodeSubtree(field, fullKey, value, sensitive)
+			if err != nil {
+				return err
+			}
+			continue
+		}
 
 		// Check if the field implements PropUnmarshaler
-		if pu, ok := field.Addr().Interface().(PropUnmarshaller); ok {
-			key, valStr, err := extractKeyValue(propertyKey, value)
+		if _, ok := field.Addr().Interface().(PropUnmarshaller); ok {
+			_, valStr, err := extractKeyValue(propertyKey, value)
 			if err != nil {
-				return fmt.Errorf("error extracting key-value for field '%s': %v", propertyKey, err)
+				return fmt.Errorf("error extracting key-value for field '%s': %v", fullKey, err)
 			}
-			err = pu.UnmarshalProp(key, valStr)
+			err = d.decodeValue(field, propertyKey, fullKey, valStr, sensitive)
 			if err != nil {
-				return fmt.Errorf("error unmarshaling field '%s': %v", propertyKey, err)
+				return err
+			}
+			continue
+		}
+
+		// Decode maps from the keys below the field
+		if field.Kind() == reflect.Map {
+			if subProps, ok := value.(map[string]interface{}); ok {
+				err := d.decodeMap(field, fieldType, fullKey, subProps, sensitive)
+				if err != nil {
+					return err
+				}
+			} else if valueStr, ok := value.(string); ok && d.decodesLeaf(field.Type()) {
+				err := d.decodeValue(field, propertyKey, fullKey, valueStr, sensitive)
+				if err != nil {
+					return err
+				}
+			} else {
+				return fmt.Errorf("expected map for map field '%s'%s, got %T", fullKey, from, value)
 			}
 			continue
 		}
@@ -144,12 +432,17 @@ func setStructFields(structVal reflect.Value, props map[string]interface{}) erro
 		if field.Kind() == reflect.Struct {
 			// The properties should be nested under propertyKey
 			if subProps, ok := value.(map[string]interface{}); ok {
-				err := setStructFields(field, subProps)
+				err := d.decodeNested(fullKey, field, subProps, sensitive)
+				if err != nil {
+					return err
+				}
+			} else if valueStr, ok := value.(string); ok && d.decodesLeaf(field.Type()) {
+				err := d.decodeValue(field, propertyKey, fullKey, valueStr, sensitive)
 				if err != nil {
 					return err
 				}
 			} else {
-				return fmt.Errorf("expected map for nested struct field '%s', got %T", propertyKey, value)
+				return fmt.Errorf("expected map for nested struct field '%s'%s, got %T", fullKey, from, value)
 			}
 			continue
 		}
@@ -159,42 +452,86 @@ func setStructFields(structVal reflect.Value, props map[string]interface{}) erro
 			if valueMap, ok := value.(map[string]interface{}); ok {
 				if field.IsNil() {
 					field.Set(reflect.New(field.Type().Elem()))
+					setDefaults(field.Elem())
 				}
-				err := setStructFields(field.Elem(), valueMap)
+				err := d.decodeNested(fullKey, field.Elem(), valueMap, sensitive)
 				if err != nil {
 					return err
 				}
-			} else {
-				return fmt.Errorf("expected map for nested struct pointer field '%s', got %T", propertyKey, value)
-			}
-			continue
-		}
-
-		// Check if the field implements TextUnmarshaler
-		if field.CanInterface() {
-			if unmarshaler, ok := field.Addr().Interface().(TextUnmarshaler); ok {
-				err := unmarshaler.UnmarshalText([]byte(value.(string)))
+			} else if valueStr, ok := value.(string); ok && d.decodesLeaf(field.Type()) {
+				err := d.decodeValue(field, propertyKey, fullKey, valueStr, sensitive)
 				if err != nil {
-					return fmt.Errorf("error unmarshaling field '%s': %v", propertyKey, err)
+					return err
 				}
-				continue
+			} else {
+				return fmt.Errorf("expected map for nested struct pointer field '%s'%s, got %T", fullKey, from, value)
 			}
+			continue
 		}
 
 		// Set the field value
-		if valueStr, ok := value.(string); ok {
-			err := setFieldValue(field, valueStr)
-			if err != nil {
-				return fmt.Errorf("error setting field '%s': %v", propertyKey, err)
-			}
-		} else {
-			return fmt.Errorf("expected string value for field '%s', got %T", propertyKey, value)
+		valueStr, ok := value.(string)
+		if !ok {
+			return fmt.Errorf("expected string value for field '%s', got %T", fullKey, value)
+		}
+		err := d.decodeValue(field, propertyKey, fullKey, valueStr, sensitive)
+		if err != nil {
+			return err
 		}
 	}
 
 	return nil
 }
 
+// decodeNested decodes a nested struct. Every field below a sensitive field is
+// sensitive too.
+func (d *decodeState) decodeNested(prefix string, structVal reflect.Value, props map[string]interface{}, sensitive bool) error {
+	secret := d.secret
+	d.secret = sensitive
+	defer func() { d.secret = secret }()
+	return d.decodeStruct(prefix, structVal, props)
+}
+
+// decodeValue decodes the raw value of fullKey into field. If the field is
+// sensitive, its value is left out of errors and the key is marked sensitive
+// in the property set.
+func (d *decodeState) decodeValue(field reflect.Value, propertyKey, fullKey, raw string, sensitive bool) error {
+	if sensitive && d.props != nil {
+		d.props.MarkSensitive(fullKey)
+	}
+
+	valueStr, err := d.resolve(fullKey, raw, sensitive)
+	if err != nil {
+		return err
+	}
+	return d.unmarshalText(field, propertyKey, fullKey, valueStr, sensitive)
+}
+
+// resolve expands the placeholders in the value of fullKey and decrypts it
+// if it is an ENC(...) value.
+func (d *decodeState) resolve(fullKey, value string, sensitive bool) (string, error) {
+	if d.interp != nil {
+		resolved, err := d.interp.resolve(fullKey, value)
+		if err != nil {
+			return "", fmt.Errorf("error resolving field '%s'%s: %w", fullKey, originSuffix(d.props, fullKey), redactCause(err, sensitive))
+		}
+		value = resolved
+	}
+
+	if ciphertext, ok := encryptedValue(value); ok {
+		if d.decrypter == nil {
+			return "", fmt.Errorf("field '%s'%s is encrypted but no decrypter is configured", fullKey, originSuffix(d.props, fullKey))
+		}
+		plaintext, err := d.decrypter.Decrypt(ciphertext)
+		if err != nil {
+			return "", fmt.Errorf("error decrypting field '%s'%s: %w", fullKey, originSuffix(d.props, fullKey), redactCause(err, sensitive))
+		}
+		value = plaintext
+	}
+
+	return value, nil
+}
+
 // Helper function to extract key-value pair for PropUnmarshaler
 func extractKeyValue(propertyKey string, value interface{}) (string, string, error) {
 	valueStr, ok := value.(string)
@@ -227,13 +564,13 @@ func setFieldValue(field reflect.Value, valueStr string) error {
 		}
 		field.SetBool(boolVal)
 	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
-		intVal, err := strconv.ParseInt(valueStr, 10, field.Type().Bits())
+		intVal, err := strconv.ParseInt(valueStr, intBase(valueStr), field.Type().Bits())
 		if err != nil {
 			return fmt.Errorf("invalid integer value '%s' for field", valueStr)
 		}
 		field.SetInt(intVal)
 	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
-		uintVal, err := strconv.ParseUint(valueStr, 10, field.Type().Bits())
+		uintVal, err := strconv.ParseUint(valueStr, intBase(valueStr), field.Type().Bits())
 		if err != nil {
 			return fmt.Errorf("invalid unsigned integer value '%s' for field", valueStr)
 		}
@@ -58,6 +58,76 @@ key2=value2
 	}
 }
 
+// TestParseSeparatorsAndContinuations ensures that ":" separators and
+// backslash line continuations are read in JavaSyntax.
+func TestParseSeparatorsAndContinuations(t *testing.T) {
+	data := []byte(`key1: value1
+key2 = first \
+    second \
+    third
+key3=ends with \\
+key4=http://host:8080
+# comment \
+key5=value5
+`)
+
+	p, err := JavaSyntax.Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"key1": "value1",
+		"key2": "first second third",
+		"key3": `ends with \`,
+		"key4": "http://host:8080",
+		"key5": "value5",
+	}
+	if p.Len() != len(expected) {
+		t.Errorf("Expected %d keys, got %v", len(expected), p.Keys())
+	}
+	for key, value := range expected {
+		if got, _ := p.Get(key); got != value {
+			t.Errorf("Expected %s to be %q, got %q", key, value, got)
+		}
+	}
+
+	// Continued values keep the line they start on
+	if origin, _ := p.Origin("key3"); origin.Line != 5 {
+		t.Errorf("Expected key3 on line 5, got %d", origin.Line)
+	}
+}
+
+// TestParsePlainSyntax ensures that Parse splits lines at the first "=" only
+// and leaves backslashes alone.
+func TestParsePlainSyntax(t *testing.T) {
+	data := []byte(`dir=C:\temp\
+name=x
+share=\\srv\data
+urn:isbn=1
+`)
+
+	p, err := Parse(data)
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+
+	expected := map[string]string{
+		"dir":      `C:\temp\`,
+		"name":     "x",
+		"share":    `\\srv\data`,
+		"urn:isbn": "1",
+	}
+	if p.Len() != len(expected) {
+		t.Errorf("Expected %d keys, got %v", len(expected), p.Keys())
+	}
+	for key, value := range expected {
+		if got, _ := p.Get(key); got != value {
+			t.Errorf("Expected %s to be %q, got %q", key, value, got)
+		}
+	}
+}
+
 // TestSetStructFields_Simple tests setStructFields with a simple struct and correct property values.
 func TestSetStructFields_Simple(t *testing.T) {
 	type Config struct {
@@ -0,0 +1,93 @@
+package dotprops
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Origin describes where a property value came from.
+type Origin struct {
+	// Source is the file path, "env", "flags", "defaults" or the name given
+	// to Bytes or Map.
+	Source string
+	// Line is the 1-based line number within Source, or 0 when the source is
+	// not line based.
+	Line int
+	// Name is the environment variable, flag or struct field that supplied
+	// the value, when applicable.
+	Name string
+}
+
+// String formats the origin as "file:line", "env NAME", "flags name" and so on.
+func (o Origin) String() string {
+	source := o.Source
+	if source == "" {
+		source = "<input>"
+	}
+	switch {
+	case o.Line > 0:
+		return fmt.Sprintf("%s:%d", source, o.Line)
+	case o.Name != "":
+		return source + " " + o.Name
+	default:
+		return source
+	}
+}
+
+// Value is a property value together with its origin.
+type Value struct {
+	Text   string
+	Origin Origin
+}
+
+// Origin returns where the current value of key came from.
+func (p *Properties) Origin(key string) (Origin, bool) {
+	e, ok := p.entries[key]
+	if !ok {
+		return Origin{}, false
+	}
+	return e.origin, true
+}
+
+// Shadowed returns the lower-priority values that the current value of key
+// replaced, most recent first.
+func (p *Properties) Shadowed(key string) []Value {
+	e, ok := p.entries[key]
+	if !ok {
+		return nil
+	}
+	return append([]Value(nil), e.shadowed...)
+}
+
+// Explain returns a human-readable report listing, for every key, its final
+// value, where it came from and the values it shadowed. Values of sensitive
+// keys are redacted.
+func (p *Properties) Explain() string {
+	var sb strings.Builder
+	for _, key := range p.keys {
+		e := p.entries[key]
+		value := e.value
+		if p.sensitive[key] {
+			value = redactedValue
+		}
+		fmt.Fprintf(&sb, "%s=%s (%s)\n", key, value, e.origin)
+		for _, v := range e.shadowed {
+			if p.sensitive[key] {
+				v.Text = redactedValue
+			}
+			fmt.Fprintf(&sb, "    shadows %s (%s)\n", v.Text, v.Origin)
+		}
+	}
+	return sb.String()
+}
+
+// originSuffix formats the origin of key for use in error messages.
+func originSuffix(p *Properties, key string) string {
+	if p == nil {
+		return ""
+	}
+	if origin, ok := p.Origin(key); ok {
+		return fmt.Sprintf(" (from %s)", origin)
+	}
+	return ""
+}
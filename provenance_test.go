@@ -0,0 +1,107 @@
+package dotprops
+
+import (
+	"flag"
+	"strings"
+	"testing"
+)
+
+func TestParseRecordsOrigin(t *testing.T) {
+	data := []byte(`
+# comment
+app.name=First
+app.port=8080
+app.name=Second
+`)
+
+	props, err := parse("app.properties", data, PlainSyntax)
+	if err != nil {
+		t.Fatalf("parse failed: %v", err)
+	}
+
+	origin, ok := props.Origin("app.name")
+	if !ok {
+		t.Fatal("Expected origin for app.name")
+	}
+	if origin.String() != "app.properties:5" {
+		t.Errorf("Expected origin 'app.properties:5', got '%s'", origin)
+	}
+
+	shadowed := props.Shadowed("app.name")
+	if len(shadowed) != 1 || shadowed[0].Text != "First" || shadowed[0].Origin.Line != 3 {
+		t.Errorf("Expected app.name to shadow 'First' from line 3, got %+v", shadowed)
+	}
+
+	if _, ok := props.Origin("missing"); ok {
+		t.Error("Expected no origin for a missing key")
+	}
+}
+
+func TestLoaderProvenance(t *testing.T) {
+	type Config struct {
+		Port int    `property:"app.port" default:"80"`
+		Name string `property:"app.name"`
+	}
+
+	t.Setenv("MYAPP_APP_PORT", "9191")
+
+	flags := flag.NewFlagSet("test", flag.ContinueOnError)
+	flags.String("app.name", "", "")
+	if err := flags.Parse([]string{"-app.name=FlagApp"}); err != nil {
+		t.Fatal(err)
+	}
+
+	loader := NewLoader(
+		Bytes("base.properties", []byte("app.name=BaseApp\napp.port=8080\n")),
+		Env("MYAPP"),
+		Flags(flags),
+	)
+
+	var config Config
+	props, err := loader.Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	origin, _ := props.Origin("app.port")
+	if origin.String() != "env MYAPP_APP_PORT" {
+		t.Errorf("Expected origin 'env MYAPP_APP_PORT', got '%s'", origin)
+	}
+
+	shadowed := props.Shadowed("app.port")
+	if len(shadowed) != 2 {
+		t.Fatalf("Expected 2 shadowed values, got %+v", shadowed)
+	}
+	if shadowed[0].Origin.String() != "base.properties:2" {
+		t.Errorf("Expected first shadowed origin 'base.properties:2', got '%s'", shadowed[0].Origin)
+	}
+	if shadowed[1].Origin.String() != "defaults Config.Port" {
+		t.Errorf("Expected second shadowed origin 'defaults Config.Port', got '%s'", shadowed[1].Origin)
+	}
+
+	expected := `app.port=9191 (env MYAPP_APP_PORT)
+    shadows 8080 (base.properties:2)
+    shadows 80 (defaults Config.Port)
+app.name=FlagApp (flags app.name)
+    shadows BaseApp (base.properties:1)
+`
+	if report := props.Explain(); report != expected {
+		t.Errorf("Expected report:\n%s\nGot:\n%s", expected, report)
+	}
+}
+
+func TestDecodeErrorIncludesOrigin(t *testing.T) {
+	loader := NewLoader(
+		Bytes("base.properties", []byte("app.port=8080\n")),
+		Bytes("prod.properties", []byte("\napp.port=not_a_port\n")),
+	)
+
+	var config SimpleConfig
+	_, err := loader.Load(&config)
+	if err == nil {
+		t.Fatal("Expected Load to fail due to invalid integer value, but it did not")
+	}
+	if !strings.Contains(err.Error(), "'app.port' (from prod.properties:2)") {
+		t.Errorf("Expected error to name key and origin, got: %v", err)
+	}
+}
@@ -0,0 +1,164 @@
+package dotprops
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// RawProps holds the undecoded properties below the key of a field, like
+// json.RawMessage does for JSON. Values are kept exactly as written, with
+// their origins; placeholders and ENC(...) values are left unresolved.
+// Decode them later with Unmarshal, or a Decoder:
+//
+//	type Config struct {
+//	    Plugin dotprops.RawProps `property:"plugin"`
+//	}
+//
+//	err := config.Plugin.Unmarshal(&pluginConfig)
+type RawProps struct {
+	prefix string
+	props  *Properties
+
+	// host is the whole property set the sub-tree was captured from, for
+	// resolving placeholders, and decoder the Decoder that captured it.
+	host    *Properties
+	decoder *Decoder
+}
+
+// rawPropsType is the reflect.Type of RawProps.
+var rawPropsType = reflect.TypeOf(RawProps{})
+
+// Prefix returns the full key the properties were found under.
+func (r RawProps) Prefix() string {
+	return r.prefix
+}
+
+// Properties returns the captured properties, keyed relative to the prefix.
+func (r RawProps) Properties() *Properties {
+	p := NewProperties()
+	if r.props != nil {
+		p.Merge(r.props)
+	}
+	return p
+}
+
+// Unmarshal decodes the captured properties into the struct pointed to by v,
+// with the options of the Decoder that captured them. Keys are relative to
+// the prefix, while placeholders are resolved against the full property set
+// the properties were captured from. The `default` tags of v apply as in
+// Loader.Load.
+func (r RawProps) Unmarshal(v interface{}) error {
+	val := reflect.ValueOf(v)
+
+	// Ensure v is a pointer to a struct
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return errors.New("unmarshal expects a pointer to a struct")
+	}
+
+	dec := r.decoder
+	if dec == nil {
+		dec = NewDecoder()
+	}
+	p := r.Properties()
+	if !dec.overlay {
+		merged := tagDefaults(val.Elem().Type(), p)
+		merged.Merge(p)
+		p = merged
+	}
+
+	host, prefix := r.host, r.prefix
+	if host == nil {
+		host, prefix = p, ""
+	}
+	return dec.decodeWithin(p, val.Elem(), host, prefix)
+}
+
+// Marshal returns the captured properties as key=value lines, with their
+// original prefix.
+func (r RawProps) Marshal() ([]byte, error) {
+	if r.props == nil {
+		return nil, nil
+	}
+	var sb strings.Builder
+	for _, key := range r.props.keys {
+		sb.WriteString(fmt.Sprintf("%s=%s\n", joinKey(r.prefix, key), r.props.entries[key].value))
+	}
+	return []byte(sb.String()), nil
+}
+
+// isRawProps reports whether t is RawProps or a pointer to it.
+func isRawProps(t reflect.Type) bool {
+	return t == rawPropsType || t == reflect.PointerTo(rawPropsType)
+}
+
+// decodeRaw captures the properties below fullKey in a RawProps field. The
+// flat property set supplies the values with their origins; without one, the
+// nested map is flattened in key order.
+func (d *decodeState) decodeRaw(field reflect.Value, fullKey string, value interface{}, sensitive bool) error {
+	subProps, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected map for raw field '%s'%s, got %T", fullKey, originSuffix(d.props, fullKey), value)
+	}
+
+	raw := RawProps{prefix: fullKey, props: NewProperties(), host: d.props, decoder: d.decoder}
+	if d.props != nil {
+		for _, key := range d.props.keys {
+			if rel, ok := strings.CutPrefix(key, fullKey+"."); ok {
+				e := *d.props.entries[key]
+				e.shadowed = append([]Value(nil), e.shadowed...)
+				raw.props.put(rel, &e)
+				if sensitive {
+					d.props.MarkSensitive(key)
+				}
+				if sensitive || d.props.sensitive[key] {
+					raw.props.sensitive[rel] = true
+				}
+			}
+		}
+	} else {
+		flattenMap("", subProps, raw.props)
+	}
+
+	if field.Kind() == reflect.Ptr {
+		field.Set(reflect.New(rawPropsType))
+		field = field.Elem()
+	}
+	field.Set(reflect.ValueOf(raw))
+	return nil
+}
+
+// flattenMap adds the values of a nested map to p under their dot-separated
+// keys, in sorted order.
+func flattenMap(prefix string, props map[string]interface{}, p *Properties) {
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		switch v := props[k].(type) {
+		case map[string]interface{}:
+			flattenMap(joinKey(prefix, k), v, p)
+		case string:
+			p.Set(joinKey(prefix, k), v)
+		}
+	}
+}
+
+// encodeRaw writes the properties of a RawProps field below fullKey.
+func (e *encodeState) encodeRaw(raw RawProps, fullKey string, redact bool, props *Properties) {
+	if raw.props == nil {
+		return
+	}
+	for _, key := range raw.props.keys {
+		value := raw.props.entries[key].value
+		if redact || (e.redact && raw.props.sensitive[key]) {
+			value = redactedValue
+		}
+		props.Set(joinKey(fullKey, key), value)
+	}
+}
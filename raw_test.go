@@ -0,0 +1,160 @@
+package dotprops
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+type HostConfig struct {
+	Name   string    `property:"name"`
+	Plugin RawProps  `property:"plugin"`
+	Extra  *RawProps `property:"extra"`
+}
+
+type PluginConfig struct {
+	URL     string `property:"url"`
+	Retries int    `property:"retry.count"`
+}
+
+func TestUnmarshalRawProps(t *testing.T) {
+	data := []byte(`
+name=host
+plugin.url=${base.url}/plugin
+plugin.retry.count=3
+base.url=http://localhost
+`)
+
+	var config HostConfig
+	if err := Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.Plugin.Prefix() != "plugin" {
+		t.Errorf("Expected prefix 'plugin', got '%s'", config.Plugin.Prefix())
+	}
+	if config.Extra != nil {
+		t.Errorf("Expected Extra to stay nil, got %+v", config.Extra)
+	}
+
+	// Values are captured untouched, with their origins
+	props := config.Plugin.Properties()
+	if value, _ := props.Get("url"); value != "${base.url}/plugin" {
+		t.Errorf("Expected raw value '${base.url}/plugin', got '%s'", value)
+	}
+	if origin, _ := props.Origin("retry.count"); origin.Line != 4 {
+		t.Errorf("Expected retry.count from line 4, got %v", origin)
+	}
+
+	var plugin PluginConfig
+	if err := NewDecoder(WithInterpolation(false)).Decode(props, &plugin); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if plugin.URL != "${base.url}/plugin" || plugin.Retries != 3 {
+		t.Errorf("Expected {${base.url}/plugin 3}, got %+v", plugin)
+	}
+
+	data, err := config.Plugin.Marshal()
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "plugin.url=${base.url}/plugin\nplugin.retry.count=3\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestRawPropsUnmarshal(t *testing.T) {
+	var config HostConfig
+	if err := Unmarshal([]byte("extra.url=http://x\nextra.retry.count=2\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Extra == nil {
+		t.Fatal("Expected Extra to be allocated")
+	}
+
+	var plugin PluginConfig
+	if err := config.Extra.Unmarshal(&plugin); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if plugin.URL != "http://x" || plugin.Retries != 2 {
+		t.Errorf("Expected {http://x 2}, got %+v", plugin)
+	}
+}
+
+func TestRawPropsUnmarshalResolvesAgainstHost(t *testing.T) {
+	type DirConfig struct {
+		Dir     string `property:"dir"`
+		Retries int    `property:"retry.count" default:"5"`
+	}
+
+	data := []byte(`
+app.home=/opt/app
+plugin.dir=${app.home}/p
+`)
+
+	var config HostConfig
+	if err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	var plugin DirConfig
+	if err := config.Plugin.Unmarshal(&plugin); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if plugin.Dir != "/opt/app/p" || plugin.Retries != 5 {
+		t.Errorf("Expected {/opt/app/p 5}, got %+v", plugin)
+	}
+
+	// Without interpolation in the host, the placeholder stays as is
+	if err := Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if err := config.Plugin.Unmarshal(&plugin); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if plugin.Dir != "${app.home}/p" {
+		t.Errorf("Expected Dir '${app.home}/p', got '%s'", plugin.Dir)
+	}
+}
+
+func TestSetStructFieldsRawProps(t *testing.T) {
+	props, err := parseProperties([]byte("plugin.url=http://x\nplugin.retry.count=2\n"))
+	if err != nil {
+		t.Fatalf("parseProperties failed: %v", err)
+	}
+
+	var config HostConfig
+	if err := setStructFields(reflect.ValueOf(&config).Elem(), props); err != nil {
+		t.Fatalf("setStructFields failed: %v", err)
+	}
+
+	data, _ := config.Plugin.Marshal()
+	expected := "plugin.retry.count=2\nplugin.url=http://x\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+}
+
+func TestMarshalRawProps(t *testing.T) {
+	var config HostConfig
+	if err := Unmarshal([]byte("name=host\nplugin.url=http://x\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	data, err := Marshal(&config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if string(data) != "name=host\nplugin.url=http://x\n" {
+		t.Errorf("Expected the raw properties in the output, got:\n%s", data)
+	}
+}
+
+func TestUnmarshalRawPropsLeafValue(t *testing.T) {
+	var config HostConfig
+	err := Unmarshal([]byte("plugin=x\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "expected map for raw field 'plugin'") {
+		t.Errorf("Expected a raw field error, got: %v", err)
+	}
+}
@@ -0,0 +1,67 @@
+package dotprops
+
+import (
+	"strconv"
+	"strings"
+)
+
+// redactedValue replaces sensitive values in output and errors.
+const redactedValue = "******"
+
+// MarkSensitive marks keys as sensitive so that Explain redacts their values.
+// Loader.Load marks the keys of fields tagged secret or sensitive in the set
+// it returns automatically.
+func (p *Properties) MarkSensitive(keys ...string) {
+	for _, key := range keys {
+		p.sensitive[key] = true
+	}
+}
+
+// IsSensitive reports whether key has been marked sensitive.
+func (p *Properties) IsSensitive(key string) bool {
+	return p.sensitive[key]
+}
+
+// Redacted formats v like Marshal on a single line, for logging, with the
+// values of fields tagged secret or sensitive replaced by ******. v must be a
+// struct or a pointer to a struct.
+func Redacted(v interface{}) string {
+	data, err := NewEncoder(WithRedaction()).Marshal(v)
+	if err != nil {
+		return "<dotprops: " + err.Error() + ">"
+	}
+
+	var pairs []string
+	for _, line := range strings.Split(string(data), "\n") {
+		// Skip the comments and blank lines of documented fields
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, _ := strings.Cut(line, "=")
+		if value == "" || strings.ContainsAny(value, " \t\"=") {
+			value = strconv.Quote(value)
+		}
+		pairs = append(pairs, key+"="+value)
+	}
+	return strings.Join(pairs, " ")
+}
+
+// redactedError stands in for the cause of an error about a sensitive value,
+// whose message may quote the value. The cause stays in the chain for
+// errors.Is and errors.As.
+type redactedError struct {
+	err error
+}
+
+func (e *redactedError) Error() string { return "invalid value " + redactedValue }
+
+func (e *redactedError) Unwrap() error { return e.err }
+
+// redactCause returns err, or err behind a redactedError if the value it is
+// about is sensitive.
+func redactCause(err error, sensitive bool) error {
+	if !sensitive {
+		return err
+	}
+	return &redactedError{err: err}
+}
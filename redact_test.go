@@ -0,0 +1,147 @@
+package dotprops
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type SecretConfig struct {
+	User     string `property:"db.user"`
+	Password string `property:"db.password,secret"`
+	PIN      int    `property:"db.pin,sensitive"`
+	Vault    struct {
+		Token string `property:"token"`
+	} `property:"vault,secret"`
+}
+
+func TestUnmarshalSensitiveErrorRedacted(t *testing.T) {
+	data := []byte(`
+db.user=admin
+db.pin=hunter2
+`)
+
+	var config SecretConfig
+	err := Unmarshal(data, &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to invalid integer value, but it did not")
+	}
+	expected := "error setting field 'db.pin' (from <input>:3): invalid value ******"
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err)
+	}
+
+	// Secrets that occur in the key or the message are not scrubbed from them
+	type PortConfig struct {
+		Port int `property:"port,secret"`
+	}
+	var portConfig PortConfig
+	err = Unmarshal([]byte("port=o\n"), &portConfig)
+	expected = "error setting field 'port' (from <input>:1): invalid value ******"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error %q, got %v", expected, err)
+	}
+}
+
+// CheckedToken rejects every value with a ValidationError.
+type CheckedToken string
+
+func (c *CheckedToken) UnmarshalText(text []byte) error {
+	return &ValidationError{Errors: []*FieldError{{Err: errors.New("bad token " + string(text))}}}
+}
+
+func TestUnmarshalSensitiveErrorChain(t *testing.T) {
+	type Config struct {
+		Token CheckedToken `property:"token,secret"`
+	}
+
+	var config Config
+	err := Unmarshal([]byte("token=hunter2\n"), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to an invalid token, but it did not")
+	}
+	if strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected error not to contain the secret, got: %v", err)
+	}
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Errorf("Expected the error to wrap a *ValidationError, got %T", err)
+	}
+}
+
+func TestUnmarshalNonSensitiveErrorKeepsValue(t *testing.T) {
+	var config SimpleConfig
+	err := Unmarshal([]byte("app.port=not_a_port\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "not_a_port") {
+		t.Errorf("Expected error to show the invalid value, got: %v", err)
+	}
+}
+
+func TestExplainRedactsSensitiveKeys(t *testing.T) {
+	loader := NewLoader(
+		Bytes("base", []byte("db.user=admin\ndb.password=first\nvault.token=abc\n")),
+		Bytes("override", []byte("db.password=second\n")),
+	)
+
+	var config SecretConfig
+	props, err := loader.Load(&config)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	if config.Password != "second" || config.Vault.Token != "abc" {
+		t.Errorf("Expected secrets to be decoded, got %+v", config)
+	}
+	if !props.IsSensitive("db.password") || !props.IsSensitive("vault.token") || props.IsSensitive("db.user") {
+		t.Errorf("Expected db.password and vault.token to be marked sensitive")
+	}
+
+	report := props.Explain()
+	for _, secret := range []string{"first", "second", "abc"} {
+		if strings.Contains(report, secret) {
+			t.Errorf("Expected report not to contain %q, got:\n%s", secret, report)
+		}
+	}
+	if !strings.Contains(report, "db.user=admin") {
+		t.Errorf("Expected report to show non-sensitive values, got:\n%s", report)
+	}
+}
+
+func TestMarshalWithRedaction(t *testing.T) {
+	config := &SecretConfig{User: "admin", Password: "hunter2", PIN: 1234}
+	config.Vault.Token = "abc"
+
+	data, err := NewEncoder(WithRedaction()).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := "db.password=******\ndb.pin=******\ndb.user=admin\nvault.token=******\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	// Without the option secrets are written as is
+	data, err = Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	if !strings.Contains(string(data), "db.password=hunter2") {
+		t.Errorf("Expected plain Marshal to keep the secret, got:\n%s", data)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	config := SecretConfig{User: "the admin", Password: "hunter2"}
+
+	expected := `db.password=****** db.pin=****** db.user="the admin" vault.token=******`
+	if got := Redacted(config); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if got := Redacted(&config); got != expected {
+		t.Errorf("Expected %s, got %s", expected, got)
+	}
+	if got := Redacted("not a struct"); !strings.HasPrefix(got, "<dotprops: ") {
+		t.Errorf("Expected an error description, got %s", got)
+	}
+}
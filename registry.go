@@ -0,0 +1,173 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// defaultDiscriminator is the key, below the prefix of an interface field,
+// that names the concrete type to decode.
+const defaultDiscriminator = "type"
+
+// registry maps the discriminator names of interface types to the concrete
+// types registered for them.
+var registry = struct {
+	sync.RWMutex
+	types map[reflect.Type]map[string]reflect.Type
+	names map[reflect.Type]map[reflect.Type]string
+}{
+	types: make(map[reflect.Type]map[string]reflect.Type),
+	names: make(map[reflect.Type]map[reflect.Type]string),
+}
+
+// RegisterType registers the concrete type of value under name for fields of
+// the interface type that iface points to, so that such fields can be
+// decoded from a discriminator key:
+//
+//	dotprops.RegisterType((*Sink)(nil), "kafka", &KafkaSink{})
+//
+// A field `property:"sink"` of type Sink is then decoded from
+// sink.type=kafka and the other keys below sink. The discriminator key can
+// be changed with the discriminator= tag option. Marshal writes the name of
+// the registered type back to it.
+//
+// value may be a struct or a pointer to a struct; decoded fields hold the
+// same kind. RegisterType panics if value does not implement the interface or
+// if name or the type is already registered for it.
+func RegisterType(iface interface{}, name string, value interface{}) {
+	ifaceType := reflect.TypeOf(iface)
+	if ifaceType == nil || ifaceType.Kind() != reflect.Ptr || ifaceType.Elem().Kind() != reflect.Interface {
+		panic("dotprops: RegisterType expects a pointer to an interface type")
+	}
+	ifaceType = ifaceType.Elem()
+
+	typ := reflect.TypeOf(value)
+	structType := typ
+	if structType != nil && structType.Kind() == reflect.Ptr {
+		structType = structType.Elem()
+	}
+	if structType == nil || structType.Kind() != reflect.Struct {
+		panic("dotprops: RegisterType expects a struct or a pointer to a struct")
+	}
+	if !typ.Implements(ifaceType) {
+		panic(fmt.Sprintf("dotprops: %s does not implement %s", typ, ifaceType))
+	}
+
+	registry.Lock()
+	defer registry.Unlock()
+
+	if _, ok := registry.types[ifaceType][name]; ok {
+		panic(fmt.Sprintf("dotprops: type name %q registered twice for %s", name, ifaceType))
+	}
+	if _, ok := registry.names[ifaceType][typ]; ok {
+		panic(fmt.Sprintf("dotprops: %s registered twice for %s", typ, ifaceType))
+	}
+	if registry.types[ifaceType] == nil {
+		registry.types[ifaceType] = make(map[string]reflect.Type)
+		registry.names[ifaceType] = make(map[reflect.Type]string)
+	}
+	registry.types[ifaceType][name] = typ
+	registry.names[ifaceType][typ] = name
+}
+
+// registeredType returns the concrete type registered under name for ifaceType.
+func registeredType(ifaceType reflect.Type, name string) (reflect.Type, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	typ, ok := registry.types[ifaceType][name]
+	return typ, ok
+}
+
+// hasRegisteredTypes reports whether any type is registered for ifaceType.
+func hasRegisteredTypes(ifaceType reflect.Type) bool {
+	registry.RLock()
+	defer registry.RUnlock()
+	return len(registry.types[ifaceType]) > 0
+}
+
+// registeredName returns the name under which typ is registered for ifaceType.
+func registeredName(ifaceType, typ reflect.Type) (string, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	name, ok := registry.names[ifaceType][typ]
+	return name, ok
+}
+
+// discriminator returns the discriminator key of an interface field, relative
+// to its own key.
+func discriminator(field reflect.StructField) string {
+	_, opts := parseTag(field)
+	if keys := opts.values("discriminator"); len(keys) > 0 {
+		return keys[0]
+	}
+	return defaultDiscriminator
+}
+
+// decodeInterface instantiates the type registered under the discriminator
+// of an interface field and decodes props into it.
+func (d *decodeState) decodeInterface(field reflect.Value, fieldType reflect.StructField, fullKey string, props map[string]interface{}, sensitive bool) error {
+	discKey := discriminator(fieldType)
+	discFullKey := joinKey(fullKey, discKey)
+
+	raw, ok := getNestedProperty(props, discKey)
+	if !ok {
+		return fmt.Errorf("missing type discriminator '%s' for field '%s'", discFullKey, fullKey)
+	}
+	rawName, ok := raw.(string)
+	if !ok {
+		return fmt.Errorf("expected string value for field '%s', got %T", discFullKey, raw)
+	}
+	name, err := d.resolve(discFullKey, rawName, sensitive)
+	if err != nil {
+		return err
+	}
+
+	typ, ok := registeredType(field.Type(), name)
+	if !ok {
+		return fmt.Errorf("unknown type '%s' for field '%s'%s", name, fullKey, originSuffix(d.props, discFullKey))
+	}
+
+	// Decode into a new instance of the registered struct
+	var ptr reflect.Value
+	if typ.Kind() == reflect.Ptr {
+		ptr = reflect.New(typ.Elem())
+	} else {
+		ptr = reflect.New(typ)
+	}
+	setDefaults(ptr.Elem())
+	if err := d.decodeNested(fullKey, ptr.Elem(), props, sensitive); err != nil {
+		return err
+	}
+
+	if typ.Kind() == reflect.Ptr {
+		field.Set(ptr)
+	} else {
+		field.Set(ptr.Elem())
+	}
+	return nil
+}
+
+// encodeInterface encodes the struct held by an interface field together with
+// its discriminator.
+func (e *encodeState) encodeInterface(field reflect.Value, fieldType reflect.StructField, fullKey string, sensitive bool, props *Properties) error {
+	elem := field.Elem()
+	name, ok := registeredName(field.Type(), elem.Type())
+	if !ok {
+		return fmt.Errorf("type %s is not registered for field '%s'", elem.Type(), fullKey)
+	}
+	props.Set(joinKey(fullKey, discriminator(fieldType)), name)
+
+	if elem.Kind() == reflect.Ptr {
+		if elem.IsNil() {
+			return nil
+		}
+		leave, err := e.visit(elem, fullKey)
+		if err != nil {
+			return err
+		}
+		defer leave()
+		elem = elem.Elem()
+	}
+	return e.encodeNested(fullKey, elem, sensitive, props)
+}
@@ -0,0 +1,145 @@
+package dotprops
+
+import (
+	"strings"
+	"testing"
+)
+
+type Sink interface {
+	Kind() string
+}
+
+type KafkaSink struct {
+	Brokers string `property:"brokers"`
+	Topic   string `property:"topic" validate:"nonempty"`
+}
+
+func (*KafkaSink) Kind() string { return "kafka" }
+
+type FileSink struct {
+	Path string `property:"path"`
+}
+
+func (FileSink) Kind() string { return "file" }
+
+func init() {
+	RegisterType((*Sink)(nil), "kafka", &KafkaSink{})
+	RegisterType((*Sink)(nil), "file", FileSink{})
+}
+
+type PipelineConfig struct {
+	Name   string `property:"name"`
+	Sink   Sink   `property:"sink"`
+	Backup Sink   `property:"backup,discriminator=kind"`
+}
+
+func TestUnmarshalRegisteredType(t *testing.T) {
+	data := []byte(`
+name=events
+sink.type=kafka
+sink.brokers=localhost:9092
+sink.topic=events
+backup.kind=file
+backup.path=/var/spool/events
+`)
+
+	var config PipelineConfig
+	if err := Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	kafka, ok := config.Sink.(*KafkaSink)
+	if !ok {
+		t.Fatalf("Expected Sink to be *KafkaSink, got %T", config.Sink)
+	}
+	if kafka.Brokers != "localhost:9092" || kafka.Topic != "events" {
+		t.Errorf("Expected brokers and topic to be set, got %+v", kafka)
+	}
+
+	file, ok := config.Backup.(FileSink)
+	if !ok {
+		t.Fatalf("Expected Backup to be FileSink, got %T", config.Backup)
+	}
+	if file.Path != "/var/spool/events" {
+		t.Errorf("Expected Path '/var/spool/events', got '%s'", file.Path)
+	}
+}
+
+func TestUnmarshalRegisteredTypeErrors(t *testing.T) {
+	tests := []struct {
+		data     string
+		expected string
+	}{
+		{"sink.brokers=localhost:9092\n", "missing type discriminator 'sink.type' for field 'sink'"},
+		{"sink.type=redis\n", "unknown type 'redis' for field 'sink' (from <input>:1)"},
+		{"sink=kafka\n", "expected map for interface field 'sink'"},
+		{"sink.type=kafka\n", "sink.topic: must not be empty"},
+	}
+
+	for _, tt := range tests {
+		var config PipelineConfig
+		err := Unmarshal([]byte(tt.data), &config)
+		if err == nil || !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("Expected error containing %q for %q, got: %v", tt.expected, tt.data, err)
+		}
+	}
+}
+
+func TestMarshalRegisteredType(t *testing.T) {
+	config := PipelineConfig{
+		Name:   "events",
+		Sink:   &KafkaSink{Brokers: "localhost:9092", Topic: "events"},
+		Backup: FileSink{Path: "/tmp"},
+	}
+
+	data, err := Marshal(&config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := "backup.kind=file\nbackup.path=/tmp\nname=events\nsink.brokers=localhost:9092\nsink.topic=events\nsink.type=kafka\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	// The output decodes back into the same types
+	var decoded PipelineConfig
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if *decoded.Sink.(*KafkaSink) != *config.Sink.(*KafkaSink) || decoded.Backup != config.Backup {
+		t.Errorf("Expected %+v, got %+v", config, decoded)
+	}
+}
+
+type unregisteredSink struct{}
+
+func (unregisteredSink) Kind() string { return "none" }
+
+func TestMarshalUnregisteredType(t *testing.T) {
+	config := PipelineConfig{Sink: unregisteredSink{}}
+	_, err := Marshal(&config)
+	if err == nil || !strings.Contains(err.Error(), "is not registered for field 'sink'") {
+		t.Errorf("Expected an unregistered type error, got: %v", err)
+	}
+}
+
+func TestRegisterTypePanics(t *testing.T) {
+	tests := []func(){
+		func() { RegisterType(Sink(nil), "x", FileSink{}) },
+		func() { RegisterType((*Sink)(nil), "x", "not a struct") },
+		func() { RegisterType((*Sink)(nil), "x", KafkaSink{}) },
+		func() { RegisterType((*Sink)(nil), "kafka", unregisteredSink{}) },
+	}
+
+	for i, register := range tests {
+		func() {
+			defer func() {
+				if recover() == nil {
+					t.Errorf("Expected registration %d to panic, but it did not", i)
+				}
+			}()
+			register()
+		}()
+	}
+}
@@ -0,0 +1,102 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// propsMarshalerType is the reflect.Type of the PropsMarshaler interface.
+var propsMarshalerType = reflect.TypeOf((*PropsMarshaler)(nil)).Elem()
+
+// indirectType returns the type t points to, or t if it is not a pointer.
+func indirectType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Ptr {
+		return t.Elem()
+	}
+	return t
+}
+
+// decodesSubtree reports whether values of type t decode themselves from a
+// sub-tree of properties.
+func decodesSubtree(t reflect.Type) bool {
+	return reflect.PointerTo(t).Implements(propsUnmarshallerType)
+}
+
+// decodeSubtree passes the properties below fullKey to the UnmarshalProps
+// method of field, allocating field if it is a nil pointer.
+func (d *decodeState) decodeSubtree(field reflect.Value, fullKey string, value interface{}, sensitive bool) error {
+	subProps, ok := value.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("expected map for field '%s'%s, got %T", fullKey, originSuffix(d.props, fullKey), value)
+	}
+
+	// Resolve every value of the sub-tree
+	flat := NewProperties()
+	flattenMap("", subProps, flat)
+	values := make(map[string]string, flat.Len())
+	for _, key := range flat.Keys() {
+		raw, _ := flat.Get(key)
+		subKey := joinKey(fullKey, key)
+		if sensitive && d.props != nil {
+			d.props.MarkSensitive(subKey)
+		}
+		resolved, err := d.resolve(subKey, raw, sensitive)
+		if err != nil {
+			return err
+		}
+		values[key] = resolved
+	}
+
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			field.Set(reflect.New(field.Type().Elem()))
+		}
+		field = field.Elem()
+	}
+
+	err := field.Addr().Interface().(PropsUnmarshaller).UnmarshalProps(fullKey, values)
+	if err != nil {
+		return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, originSuffix(d.props, firstKey(d.props, fullKey)), redactCause(err, sensitive))
+	}
+	return nil
+}
+
+// firstKey returns the first key of p below prefix, or prefix if there is
+// none, for reporting the origin of a sub-tree.
+func firstKey(p *Properties, prefix string) string {
+	if p == nil {
+		return prefix
+	}
+	for _, key := range p.keys {
+		if strings.HasPrefix(key, prefix+".") {
+			return key
+		}
+	}
+	return prefix
+}
+
+// encodeSubtree writes the properties returned by a PropsMarshaler below
+// fullKey, in sorted order.
+func (e *encodeState) encodeSubtree(pm PropsMarshaler, fullKey string, redact bool, props *Properties) error {
+	values, err := pm.MarshalProps(fullKey)
+	if err != nil {
+		return fmt.Errorf("error marshaling field '%s': %v", fullKey, err)
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		value := values[k]
+		if redact {
+			value = redactedValue
+		}
+		props.Set(joinKey(fullKey, k), value)
+	}
+	return nil
+}
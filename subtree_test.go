@@ -0,0 +1,158 @@
+package dotprops
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+// PoolSpec encodes itself as "min..max" plus an idle timeout, under
+// whatever key it is nested.
+type PoolSpec struct {
+	Min, Max int
+	Idle     string
+	prefix   string
+}
+
+func (p PoolSpec) MarshalProps(prefix string) (map[string]string, error) {
+	if p.Min > p.Max {
+		return nil, fmt.Errorf("%s: min exceeds max", prefix)
+	}
+	return map[string]string{
+		"size":         fmt.Sprintf("%d..%d", p.Min, p.Max),
+		"idle.timeout": p.Idle,
+	}, nil
+}
+
+func (p *PoolSpec) UnmarshalProps(prefix string, props map[string]string) error {
+	p.prefix = prefix
+	min, max, ok := strings.Cut(props["size"], "..")
+	if !ok {
+		return fmt.Errorf("invalid size %q", props["size"])
+	}
+	var err error
+	if p.Min, err = strconv.Atoi(min); err != nil {
+		return err
+	}
+	if p.Max, err = strconv.Atoi(max); err != nil {
+		return err
+	}
+	p.Idle = props["idle.timeout"]
+	return nil
+}
+
+type PoolConfig struct {
+	Primary struct {
+		Pool PoolSpec `property:"pool"`
+	} `property:"db.primary"`
+	Replica *PoolSpec `property:"db.replica.pool"`
+	Secret  PoolSpec  `property:"secret.pool,secret"`
+}
+
+func TestUnmarshalPropsUnmarshaller(t *testing.T) {
+	data := []byte(`
+idle=30s
+db.primary.pool.size=2..10
+db.primary.pool.idle.timeout=${idle}
+db.replica.pool.size=1..4
+`)
+
+	var config PoolConfig
+	if err := NewDecoder(WithInterpolation(true)).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	primary := config.Primary.Pool
+	if primary.Min != 2 || primary.Max != 10 || primary.Idle != "30s" || primary.prefix != "db.primary.pool" {
+		t.Errorf("Expected primary pool {2 10 30s db.primary.pool}, got %+v", primary)
+	}
+	if config.Replica == nil || config.Replica.Max != 4 || config.Replica.prefix != "db.replica.pool" {
+		t.Errorf("Expected replica pool to be allocated, got %+v", config.Replica)
+	}
+}
+
+func TestUnmarshalPropsUnmarshallerError(t *testing.T) {
+	var config PoolConfig
+	err := Unmarshal([]byte("db.replica.pool.size=4\nsecret.pool.size=hunter2\n"), &config)
+	if err == nil {
+		t.Fatal("Expected Unmarshal to fail due to an invalid size, but it did not")
+	}
+	expected := `error unmarshaling field 'db.replica.pool' (from <input>:1): invalid size "4"`
+	if err.Error() != expected {
+		t.Errorf("Expected error %q, got %q", expected, err)
+	}
+
+	err = Unmarshal([]byte("secret.pool.size=hunter2\n"), &config)
+	if err == nil || strings.Contains(err.Error(), "hunter2") {
+		t.Errorf("Expected a redacted error, got: %v", err)
+	}
+
+	err = Unmarshal([]byte("db.replica.pool=4\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "expected map for field 'db.replica.pool'") {
+		t.Errorf("Expected a map error, got: %v", err)
+	}
+}
+
+// RawSpec decodes itself and records the keys it receives. Its tags are not
+// used by the decoder.
+type RawSpec struct {
+	Size     string `property:"size" default:"1..2"`
+	Idle     string `property:"idle,alias=timeout" default:"5s"`
+	received map[string]string
+}
+
+func (r *RawSpec) UnmarshalProps(prefix string, props map[string]string) error {
+	r.received = props
+	return nil
+}
+
+func TestUnmarshalPropsUnmarshallerBehindPointer(t *testing.T) {
+	var config struct {
+		Spec *RawSpec `property:"spec"`
+	}
+	data := []byte("spec.size=3..4\nspec.timeout=9s\n")
+	if err := NewDecoder(WithTagDefaults()).Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	// Neither defaults nor aliases apply inside a struct that decodes itself
+	if config.Spec == nil {
+		t.Fatal("Expected Spec to be allocated")
+	}
+	expected := map[string]string{"size": "3..4", "timeout": "9s"}
+	if !reflect.DeepEqual(config.Spec.received, expected) {
+		t.Errorf("Expected %v, got %v", expected, config.Spec.received)
+	}
+}
+
+func TestMarshalPropsMarshaler(t *testing.T) {
+	var config PoolConfig
+	config.Primary.Pool = PoolSpec{Min: 2, Max: 10, Idle: "30s"}
+	config.Replica = &PoolSpec{Min: 1, Max: 4}
+	config.Secret = PoolSpec{Min: 1, Max: 1, Idle: "1s"}
+
+	data, err := NewEncoder(WithRedaction()).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"db.primary.pool.idle.timeout=30s",
+		"db.primary.pool.size=2..10",
+		"db.replica.pool.idle.timeout=",
+		"db.replica.pool.size=1..4",
+		"secret.pool.idle.timeout=******",
+		"secret.pool.size=******",
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	config.Replica.Min = 5
+	_, err = Marshal(config)
+	if err == nil || !strings.Contains(err.Error(), "error marshaling field 'db.replica.pool': db.replica.pool: min exceeds max") {
+		t.Errorf("Expected a marshaling error, got: %v", err)
+	}
+}
@@ -0,0 +1,130 @@
+package dotprops
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Syntax selects the properties syntax that is read and written.
+type Syntax int
+
+const (
+	// PlainSyntax reads one key=value pair per line, split at the first "=".
+	// Backslashes have no special meaning. This is the default.
+	PlainSyntax Syntax = iota
+	// JavaSyntax also reads ":" as a separator, continues lines ending in an
+	// unescaped backslash on the next line and reads \\, \: and \= as the
+	// escaped characters, as java.util.Properties does. A backslash before
+	// any other character is kept as is, so that C:\temp reads as written.
+	JavaSyntax
+)
+
+// WithDecodeSyntax sets the syntax Unmarshal parses, and that Loader.Load and
+// Loader.Properties parse the data of Bytes, File, FS, Dir and Profiles
+// sources in. The default is PlainSyntax.
+func WithDecodeSyntax(syntax Syntax) DecoderOption {
+	return func(d *Decoder) {
+		d.syntax = syntax
+	}
+}
+
+// WithEncodeSyntax sets the syntax of Marshal output. With JavaSyntax,
+// backslashes that would be read as an escape or a continuation are doubled
+// and separators in keys are escaped. The default is PlainSyntax, which
+// writes keys and values as they are. Output written WithSeparator(":") or
+// WithMaxLineWidth can only be read back in JavaSyntax.
+func WithEncodeSyntax(syntax Syntax) EncoderOption {
+	return func(e *Encoder) {
+		e.syntax = syntax
+	}
+}
+
+// Parse reads properties data in the syntax s into a flat, ordered property
+// set.
+func (s Syntax) Parse(data []byte) (*Properties, error) {
+	return parse("", data, s)
+}
+
+var (
+	// plainPattern matches a key and value separated by the first "=".
+	plainPattern = regexp.MustCompile(`^([^#][^=]*)=(.*)`)
+	// javaPattern matches a key and value separated by the first "=" or ":"
+	// that is not escaped with a backslash.
+	javaPattern = regexp.MustCompile(`^((?:[^#=:\\]|\\.)(?:[^=:\\]|\\.)*)[=:](.*)`)
+)
+
+// match splits a logical line into its key and value as written, or returns
+// nil if it is not a property.
+func (s Syntax) match(line string) []string {
+	if s == JavaSyntax {
+		return javaPattern.FindStringSubmatch(line)
+	}
+	return plainPattern.FindStringSubmatch(line)
+}
+
+// joinLines returns the trimmed logical line starting at physical[i] and the
+// index of its last physical line. In JavaSyntax, lines that end in an
+// unescaped backslash are joined with the next one.
+func (s Syntax) joinLines(physical []string, i int) (string, int) {
+	line := strings.TrimSpace(physical[i])
+	for s == JavaSyntax && !isComment(line) && continues(line) && i+1 < len(physical) {
+		i++
+		line = line[:len(line)-1] + strings.TrimSpace(physical[i])
+	}
+	return line, i
+}
+
+// continues reports whether a line ends in an odd number of backslashes and
+// so continues on the next line.
+func continues(line string) bool {
+	n := 0
+	for n < len(line) && line[len(line)-1-n] == '\\' {
+		n++
+	}
+	return n%2 == 1
+}
+
+// escapable lists the characters that a backslash escapes in JavaSyntax.
+const escapable = `\:=`
+
+// escapeKey escapes the separators in a key, and its backslashes as in
+// escapeValue.
+func (s Syntax) escapeKey(key string) string {
+	if s != JavaSyntax {
+		return key
+	}
+	return keyEscaper.Replace(s.escapeValue(key))
+}
+
+var keyEscaper = strings.NewReplacer(":", `\:`, "=", `\=`)
+
+// escapeValue escapes the backslashes of a value that would otherwise be
+// read as an escape or, at the end, as a line continuation.
+func (s Syntax) escapeValue(value string) string {
+	if s != JavaSyntax || !strings.Contains(value, `\`) {
+		return value
+	}
+	var sb strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] == '\\' && (i+1 == len(value) || strings.IndexByte(escapable, value[i+1]) >= 0) {
+			sb.WriteByte('\\')
+		}
+		sb.WriteByte(value[i])
+	}
+	return sb.String()
+}
+
+// unescape decodes the escaped characters of a key or value.
+func (s Syntax) unescape(str string) string {
+	if s != JavaSyntax || !strings.Contains(str, `\`) {
+		return str
+	}
+	var sb strings.Builder
+	for i := 0; i < len(str); i++ {
+		if str[i] == '\\' && i+1 < len(str) && strings.IndexByte(escapable, str[i+1]) >= 0 {
+			i++
+		}
+		sb.WriteByte(str[i])
+	}
+	return sb.String()
+}
@@ -0,0 +1,64 @@
+package dotprops
+
+import (
+	"reflect"
+	"strings"
+)
+
+// tagOptions are the comma-separated options that follow the key in a
+// `property` tag, such as "secret" in `property:"db.password,secret"`.
+type tagOptions []string
+
+// parseTag splits the `property` tag of a struct field into its key and
+// options. The key is empty if the tag does not name one.
+func parseTag(field reflect.StructField) (string, tagOptions) {
+	tag := field.Tag.Get("property")
+	key, opts, _ := strings.Cut(tag, ",")
+	if opts == "" {
+		return key, nil
+	}
+	return key, tagOptions(strings.Split(opts, ","))
+}
+
+// has reports whether the options contain name.
+func (o tagOptions) has(name string) bool {
+	for _, opt := range o {
+		if strings.TrimSpace(opt) == name {
+			return true
+		}
+	}
+	return false
+}
+
+// values returns the values of every name=value option, in order.
+func (o tagOptions) values(name string) []string {
+	var values []string
+	for _, opt := range o {
+		if value, ok := strings.CutPrefix(strings.TrimSpace(opt), name+"="); ok && value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// propertyKey returns the property key of a struct field: the key of the
+// `property` tag if present, otherwise the field name.
+func propertyKey(field reflect.StructField) string {
+	if key, _ := parseTag(field); key != "" {
+		return key
+	}
+	return field.Name
+}
+
+// skipped reports whether a struct field is tagged `property:"-"`, which
+// leaves it out of both Marshal and Unmarshal. Use `property:"-,"` for the
+// key "-".
+func skipped(field reflect.StructField) bool {
+	return field.Tag.Get("property") == "-"
+}
+
+// isSensitive reports whether a struct field is tagged secret or sensitive.
+func isSensitive(field reflect.StructField) bool {
+	_, opts := parseTag(field)
+	return opts.has("secret") || opts.has("sensitive")
+}
@@ -0,0 +1,405 @@
+package dotprops
+
+import (
+	"encoding"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// Fallback selects the encodings tried for values after PropMarshaler and
+// TextMarshaler, or PropUnmarshaller and TextUnmarshaler.
+type Fallback int
+
+const (
+	// StringerFallback writes values implementing fmt.Stringer with their
+	// String method. It applies to Marshal only; there is no way back from
+	// a String result, so such values only round-trip if they also
+	// implement TextUnmarshaler or decode from the same text.
+	StringerFallback Fallback = 1 << iota
+
+	// JSONFallback writes and reads values implementing json.Marshaler
+	// and json.Unmarshaler as JSON in a single property value, as well as
+	// values of types the package does not support otherwise, such as maps
+	// with non-string keys and slices of structs.
+	JSONFallback
+
+	// BinaryFallback writes and reads values implementing
+	// encoding.BinaryMarshaler and encoding.BinaryUnmarshaler as standard
+	// base64. It is tried before JSONFallback.
+	BinaryFallback
+)
+
+// WithEncodeFallback enables the fallback encodings in f.
+func WithEncodeFallback(f Fallback) EncoderOption {
+	return func(e *Encoder) {
+		e.fallback |= f
+	}
+}
+
+// WithDecodeFallback enables the fallback decodings in f. StringerFallback
+// has no effect on decoding.
+func WithDecodeFallback(f Fallback) DecoderOption {
+	return func(d *Decoder) {
+		d.fallback |= f
+	}
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*TextUnmarshaler)(nil)).Elem()
+	stringerType        = reflect.TypeOf((*fmt.Stringer)(nil)).Elem()
+	jsonMarshalerType   = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+	binaryMarshalerType   = reflect.TypeOf((*encoding.BinaryMarshaler)(nil)).Elem()
+	binaryUnmarshalerType = reflect.TypeOf((*encoding.BinaryUnmarshaler)(nil)).Elem()
+)
+
+// isScalar reports whether values of kind k are converted by the built-in
+// conversions.
+func isScalar(k reflect.Kind) bool {
+	switch k {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+// unmarshalText decodes text into v, trying in order a DecodeFunc or the
+// hook chain, PropUnmarshaller, TextUnmarshaler, BinaryUnmarshaler with
+// BinaryFallback, json.Unmarshaler with JSONFallback, and the built-in
+// conversions. Pointers are allocated and
+// decoded the same way, as are the comma-separated elements of slices. If
+// the value is sensitive, it is left out of errors.
+func (d *decodeState) unmarshalText(v reflect.Value, propertyKey, fullKey, text string, sensitive bool) error {
+	from := originSuffix(d.props, fullKey)
+
+	// Registered converters and hooks take precedence
+	converted, text, err := d.convert(v, text)
+	if err != nil {
+		return fmt.Errorf("error converting field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+	}
+	if converted {
+		return nil
+	}
+
+	if v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			v.Set(reflect.New(v.Type().Elem()))
+		}
+		return d.unmarshalText(v.Elem(), propertyKey, fullKey, text, sensitive)
+	}
+
+	switch u := v.Addr().Interface().(type) {
+	case PropUnmarshaller:
+		if err := u.UnmarshalProp(propertyKey, text); err != nil {
+			return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+		}
+		return nil
+	case TextUnmarshaler:
+		if err := u.UnmarshalText([]byte(text)); err != nil {
+			return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+		}
+		return nil
+	}
+
+	if u, ok := v.Addr().Interface().(encoding.BinaryUnmarshaler); ok && d.fallback&BinaryFallback != 0 {
+		data, err := base64.StdEncoding.DecodeString(text)
+		if err == nil {
+			err = u.UnmarshalBinary(data)
+		}
+		if err != nil {
+			return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+		}
+		return nil
+	}
+	if u, ok := v.Addr().Interface().(json.Unmarshaler); ok && d.fallback&JSONFallback != 0 {
+		if err := u.UnmarshalJSON([]byte(text)); err != nil {
+			return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+		}
+		return nil
+	}
+
+	// Slices of values that fit in a list are comma-separated
+	if v.Kind() == reflect.Slice && d.decodesItem(v.Type().Elem()) {
+		items := splitList(text)
+		slice := reflect.MakeSlice(v.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := d.unmarshalText(slice.Index(i), propertyKey, fullKey, item, sensitive); err != nil {
+				return err
+			}
+		}
+		v.Set(slice)
+		return nil
+	}
+
+	if !isScalar(v.Kind()) && d.fallback&JSONFallback != 0 {
+		if err := json.Unmarshal([]byte(text), v.Addr().Interface()); err != nil {
+			return fmt.Errorf("error unmarshaling field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+		}
+		return nil
+	}
+
+	if err := setFieldValue(v, text); err != nil {
+		return fmt.Errorf("error setting field '%s'%s: %w", fullKey, from, redactCause(err, sensitive))
+	}
+	return nil
+}
+
+// decodesItem reports whether values of t can be decoded from an element of
+// a comma-separated list.
+func (d *decodeState) decodesItem(t reflect.Type) bool {
+	if _, ok := d.decodeFunc(t); ok {
+		return true
+	}
+	t = indirectType(t)
+	ptr := reflect.PointerTo(t)
+	return ptr.Implements(propUnmarshallerType) || ptr.Implements(textUnmarshalerType) ||
+		(d.fallback&BinaryFallback != 0 && ptr.Implements(binaryUnmarshalerType)) || isScalar(t.Kind())
+}
+
+// decodeMap decodes the keys below fullKey into a map field with string
+// keys. Struct and map values are decoded from the keys below their map key;
+// other values take the rest of the key, dots included, as their map key.
+func (d *decodeState) decodeMap(field reflect.Value, fieldType reflect.StructField, fullKey string, props map[string]interface{}, sensitive bool) error {
+	mapType := field.Type()
+	if mapType.Key().Kind() != reflect.String {
+		return fmt.Errorf("unsupported map key type %s for field '%s'", mapType.Key(), fullKey)
+	}
+	if field.IsNil() {
+		field.Set(reflect.MakeMap(mapType))
+	}
+	return d.decodeEntries(field, fieldType, fullKey, "", props, sensitive)
+}
+
+// decodeEntries decodes the entries of props into the map m, with their keys
+// prefixed by rel.
+func (d *decodeState) decodeEntries(m reflect.Value, fieldType reflect.StructField, fullKey, rel string, props map[string]interface{}, sensitive bool) error {
+	elemType := m.Type().Elem()
+	structType := indirectType(elemType)
+
+	keys := make([]string, 0, len(props))
+	for k := range props {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		key := joinKey(rel, k)
+		entryKey := joinKey(fullKey, key)
+		mapKey := reflect.ValueOf(key).Convert(m.Type().Key())
+
+		elem := reflect.New(elemType).Elem()
+		existing := m.MapIndex(mapKey)
+		if existing.IsValid() {
+			elem.Set(existing)
+		}
+
+		raw, ok := d.present(elem, entryKey, props[k])
+		if !ok {
+			// Keep explicit nulls as entries with the zero value
+			if d.isNull(props[k]) {
+				m.SetMapIndex(mapKey, elem)
+			}
+			continue
+		}
+
+		switch value := raw.(type) {
+		case map[string]interface{}:
+			switch {
+			case elemType.Kind() == reflect.Interface && hasRegisteredTypes(elemType):
+				if err := d.decodeInterface(elem, fieldType, entryKey, value, sensitive); err != nil {
+					return err
+				}
+			case structType.Kind() == reflect.Struct && !decodesSubtree(structType):
+				structVal := elem
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						elem.Set(reflect.New(structType))
+						setDefaults(elem.Elem())
+					}
+					structVal = elem.Elem()
+				} else if !existing.IsValid() {
+					setDefaults(structVal)
+				}
+				if err := d.decodeNested(entryKey, structVal, value, sensitive); err != nil {
+					return err
+				}
+			case decodesSubtree(structType):
+				if err := d.decodeSubtree(elem, entryKey, value, sensitive); err != nil {
+					return err
+				}
+			case structType.Kind() == reflect.Map:
+				// Map values are decoded from the keys below their map key
+				mapVal := elem
+				if elem.Kind() == reflect.Ptr {
+					if elem.IsNil() {
+						elem.Set(reflect.New(structType))
+					}
+					mapVal = elem.Elem()
+				}
+				if err := d.decodeMap(mapVal, fieldType, entryKey, value, sensitive); err != nil {
+					return err
+				}
+			default:
+				if err := d.decodeEntries(m, fieldType, fullKey, key, value, sensitive); err != nil {
+					return err
+				}
+				continue
+			}
+		case string:
+			if err := d.decodeValue(elem, key, entryKey, value, sensitive); err != nil {
+				return err
+			}
+		}
+		m.SetMapIndex(mapKey, elem)
+	}
+	return nil
+}
+
+// splitList splits a comma-separated list, trimming spaces around the
+// elements. A backslash escapes a comma within an element.
+func splitList(text string) []string {
+	if strings.TrimSpace(text) == "" {
+		return nil
+	}
+	var items []string
+	var sb strings.Builder
+	for i := 0; i < len(text); i++ {
+		switch {
+		case text[i] == '\\' && i+1 < len(text) && text[i+1] == ',':
+			sb.WriteByte(',')
+			i++
+		case text[i] == ',':
+			items = append(items, strings.TrimSpace(sb.String()))
+			sb.Reset()
+		default:
+			sb.WriteByte(text[i])
+		}
+	}
+	return append(items, strings.TrimSpace(sb.String()))
+}
+
+// joinList joins the elements of a list with commas, escaping the commas
+// within them.
+func joinList(items []string) string {
+	for i, item := range items {
+		items[i] = strings.ReplaceAll(item, ",", `\,`)
+	}
+	return strings.Join(items, ",")
+}
+
+// marshalSelf encodes v with the first of TextMarshaler and the enabled
+// fmt.Stringer, encoding.BinaryMarshaler and json.Marshaler fallbacks that v
+// implements. It reports whether any of them applied.
+func (e *encodeState) marshalSelf(v reflect.Value) (string, bool, error) {
+	if impl, ok := implementation(v, textMarshalerType); ok {
+		text, err := impl.(TextMarshaler).MarshalText()
+		return string(text), true, err
+	}
+	if e.fallback&StringerFallback != 0 {
+		if impl, ok := implementation(v, stringerType); ok {
+			return impl.(fmt.Stringer).String(), true, nil
+		}
+	}
+	if e.fallback&BinaryFallback != 0 {
+		if impl, ok := implementation(v, binaryMarshalerType); ok {
+			data, err := impl.(encoding.BinaryMarshaler).MarshalBinary()
+			return base64.StdEncoding.EncodeToString(data), true, err
+		}
+	}
+	if e.fallback&JSONFallback != 0 {
+		if impl, ok := implementation(v, jsonMarshalerType); ok {
+			text, err := impl.(json.Marshaler).MarshalJSON()
+			return string(text), true, err
+		}
+	}
+	return "", false, nil
+}
+
+// marshalItem encodes a single element of a list, trying in order an
+// EncodeFunc, PropMarshaler, TextMarshaler, the enabled fallbacks and the
+// built-in conversions. Nil pointers are written as empty elements.
+func (e *encodeState) marshalItem(v reflect.Value, format string) (string, error) {
+	for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+		if v.IsNil() {
+			return "", nil
+		}
+		v = v.Elem()
+	}
+
+	if fn, ok := e.encodeFunc(v.Type()); ok {
+		return fn(v.Interface())
+	}
+	if impl, ok := implementation(v, propMarshalerType); ok {
+		_, value, err := impl.(PropMarshaler).MarshalProp()
+		return value, err
+	}
+	if text, ok, err := e.marshalSelf(v); ok {
+		return text, err
+	}
+
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), nil
+	case reflect.Bool:
+		return fmt.Sprintf("%v", v.Bool()), nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return formatNumber(v, format)
+	}
+	return "", fmt.Errorf("unsupported type %s", v.Type())
+}
+
+// encodesItem reports whether values of t can be encoded as an element of a
+// comma-separated list.
+func (e *encodeState) encodesItem(t reflect.Type) bool {
+	if _, ok := e.encodeFunc(indirectType(t)); ok {
+		return true
+	}
+	t = indirectType(t)
+	ptr := reflect.PointerTo(t)
+	implements := func(iface reflect.Type) bool {
+		return t.Implements(iface) || ptr.Implements(iface)
+	}
+	return implements(propMarshalerType) || implements(textMarshalerType) ||
+		(e.fallback&StringerFallback != 0 && implements(stringerType)) ||
+		(e.fallback&BinaryFallback != 0 && implements(binaryMarshalerType)) ||
+		(e.fallback&JSONFallback != 0 && implements(jsonMarshalerType)) ||
+		isScalar(t.Kind())
+}
+
+// encodeList encodes the elements of a slice as a comma-separated list.
+func (e *encodeState) encodeList(v reflect.Value, format string) (string, error) {
+	items := make([]string, v.Len())
+	for i := range items {
+		text, err := e.marshalItem(v.Index(i), format)
+		if err != nil {
+			return "", fmt.Errorf("element %d: %v", i, err)
+		}
+		items[i] = text
+	}
+	return joinList(items), nil
+}
+
+// encodeMap encodes the entries of a map with string keys below fullKey, in
+// key order.
+func (e *encodeState) encodeMap(v reflect.Value, fieldType reflect.StructField, fullKey string, sensitive bool, props *Properties) error {
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].String() < keys[j].String()
+	})
+	for _, k := range keys {
+		err := e.encodeField(v.MapIndex(k), fieldType, joinKey(fullKey, k.String()), sensitive, props)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,247 @@
+package dotprops
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// Level implements fmt.Stringer only.
+type Level int
+
+func (l Level) String() string {
+	return [...]string{"low", "high"}[l]
+}
+
+// Version implements json.Marshaler and json.Unmarshaler only.
+type Version struct {
+	Major, Minor int
+}
+
+func (v Version) MarshalJSON() ([]byte, error) {
+	return []byte(fmt.Sprintf(`"%d.%d"`, v.Major, v.Minor)), nil
+}
+
+func (v *Version) UnmarshalJSON(data []byte) error {
+	_, err := fmt.Sscanf(string(data), `"%d.%d"`, &v.Major, &v.Minor)
+	return err
+}
+
+// Checksum implements encoding.BinaryMarshaler and
+// encoding.BinaryUnmarshaler only.
+type Checksum [4]byte
+
+func (c Checksum) MarshalBinary() ([]byte, error) {
+	return c[:], nil
+}
+
+func (c *Checksum) UnmarshalBinary(data []byte) error {
+	if len(data) != len(c) {
+		return fmt.Errorf("expected %d bytes, got %d", len(c), len(data))
+	}
+	copy(c[:], data)
+	return nil
+}
+
+type Route struct {
+	Path string `json:"path"`
+	Port int    `json:"port"`
+}
+
+type ListConfig struct {
+	Hosts   []string          `property:"hosts"`
+	Ports   []int             `property:"ports" format:"hex"`
+	IPs     []net.IP          `property:"ips"`
+	Gateway *net.IP           `property:"gateway"`
+	Limits  map[string]int    `property:"limits"`
+	Peers   map[string]Server `property:"peers"`
+	Labels  map[string]string `property:"labels"`
+}
+
+type Server struct {
+	Host string `property:"host"`
+	Port int    `property:"port"`
+}
+
+func TestListsAndMapsRoundTrip(t *testing.T) {
+	gateway := net.ParseIP("10.0.0.1")
+	config := ListConfig{
+		Hosts:   []string{"a.example.com", "b,c"},
+		Ports:   []int{80, 443},
+		IPs:     []net.IP{net.ParseIP("10.0.0.2"), net.ParseIP("::1")},
+		Gateway: &gateway,
+		Limits:  map[string]int{"cpu": 2, "memory": 512},
+		Peers:   map[string]Server{"east": {Host: "e", Port: 1}, "west": {Host: "w", Port: 2}},
+		Labels:  map[string]string{"app.kubernetes.io/name": "demo"},
+	}
+
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+
+	expected := strings.Join([]string{
+		"gateway=10.0.0.1",
+		"hosts=a.example.com,b\\,c",
+		"ips=10.0.0.2,::1",
+		"labels.app.kubernetes.io/name=demo",
+		"limits.cpu=2",
+		"limits.memory=512",
+		"peers.east.host=e",
+		"peers.east.port=1",
+		"peers.west.host=w",
+		"peers.west.port=2",
+		"ports=0x50,0x1bb",
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded ListConfig
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected %+v, got %+v", config, decoded)
+	}
+}
+
+func TestNestedMapsRoundTrip(t *testing.T) {
+	type Config struct {
+		Matrix map[string]map[string]string `property:"m"`
+		Pools  map[string]*map[string]int   `property:"pools"`
+	}
+	pool := map[string]int{"min": 1, "max": 4}
+	config := Config{
+		Matrix: map[string]map[string]string{"a": {"b": "1", "c.d": "2"}, "e": {"f": "3"}},
+		Pools:  map[string]*map[string]int{"db": &pool},
+	}
+
+	data, err := Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "m.a.b=1\nm.a.c.d=2\nm.e.f=3\npools.db.max=4\npools.db.min=1\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded Config
+	if err := Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected %+v, got %+v", config, decoded)
+	}
+}
+
+func TestUnmarshalListElementError(t *testing.T) {
+	var config ListConfig
+	err := Unmarshal([]byte("hosts=a\nips=10.0.0.1, nope\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "error unmarshaling field 'ips' (from <input>:2)") {
+		t.Errorf("Expected an unmarshaling error, got: %v", err)
+	}
+
+	err = Unmarshal([]byte("limits=5\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "expected map for map field 'limits'") {
+		t.Errorf("Expected a map error, got: %v", err)
+	}
+}
+
+type FallbackConfig struct {
+	Level   Level          `property:"level"`
+	Version Version        `property:"version"`
+	Routes  []Route        `property:"routes"`
+	Codes   map[int]string `property:"codes"`
+}
+
+func TestFallbacks(t *testing.T) {
+	config := FallbackConfig{
+		Level:   1,
+		Version: Version{Major: 1, Minor: 2},
+		Routes:  []Route{{Path: "/", Port: 80}},
+		Codes:   map[int]string{404: "missing"},
+	}
+
+	if _, err := Marshal(config); err == nil {
+		t.Error("Expected Marshal to fail without fallbacks, but it did not")
+	}
+
+	data, err := NewEncoder(WithEncodeFallback(StringerFallback | JSONFallback)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := strings.Join([]string{
+		`codes={"404":"missing"}`,
+		`level=high`,
+		`routes=[{"path":"/","port":80}]`,
+		`version="1.2"`,
+	}, "\n") + "\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded FallbackConfig
+	data = []byte(strings.Replace(string(data), "level=high", "level=1", 1))
+	if err := NewDecoder(WithDecodeFallback(JSONFallback)).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected %+v, got %+v", config, decoded)
+	}
+}
+
+func TestSplitList(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"", nil},
+		{"a", []string{"a"}},
+		{" a , b ,", []string{"a", "b", ""}},
+		{`a\,b,c\d`, []string{"a,b", `c\d`}},
+	}
+
+	for _, tt := range tests {
+		if got := splitList(tt.input); !reflect.DeepEqual(got, tt.expected) {
+			t.Errorf("splitList(%q): Expected %q, got %q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestBinaryFallback(t *testing.T) {
+	type Config struct {
+		Sum  Checksum   `property:"sum"`
+		Sums []Checksum `property:"sums"`
+	}
+	config := Config{Sum: Checksum{1, 2, 3, 4}, Sums: []Checksum{{0xff, 0, 0, 1}, {5, 6, 7, 8}}}
+
+	data, err := NewEncoder(WithEncodeFallback(BinaryFallback)).Marshal(config)
+	if err != nil {
+		t.Fatalf("Marshal failed: %v", err)
+	}
+	expected := "sum=AQIDBA==\nsums=/wAAAQ==,BQYHCA==\n"
+	if string(data) != expected {
+		t.Errorf("Expected:\n%s\nGot:\n%s", expected, data)
+	}
+
+	var decoded Config
+	if err := NewDecoder(WithDecodeFallback(BinaryFallback)).Unmarshal(data, &decoded); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if !reflect.DeepEqual(decoded, config) {
+		t.Errorf("Expected round trip to give %+v, got %+v", config, decoded)
+	}
+
+	err = NewDecoder(WithDecodeFallback(BinaryFallback)).Unmarshal([]byte("sum=AQI=\n"), &decoded)
+	if err == nil || !strings.Contains(err.Error(), "expected 4 bytes, got 2") {
+		t.Errorf("Expected a length error, got: %v", err)
+	}
+
+	// Without the fallback the type is not supported
+	if _, err := Marshal(config); err == nil {
+		t.Error("Expected Marshal to fail without the fallback, but it did not")
+	}
+}
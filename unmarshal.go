@@ -5,7 +5,105 @@ import (
 	"reflect"
 )
 
+// Decoder decodes properties into structs. Use NewDecoder to create one.
+type Decoder struct {
+	interpolate bool
+	envLookup   bool
+	lookups     map[string]Lookup
+	decrypter   Decrypter
+	defaults    bool
+	reset       bool
+	syntax      Syntax
+	overlay     bool
+
+	deprecations func(Deprecation)
+	decodeFuncs  map[reflect.Type]DecodeFunc
+	hooks        []DecodeHook
+	fallback     Fallback
+	emptyAbsent  bool
+	nulls        bool
+}
+
+// DecoderOption configures a Decoder.
+type DecoderOption func(*Decoder)
+
+// WithInterpolation enables or disables the resolution of ${key}
+// placeholders in values. It is disabled by default, so that values
+// containing "${" decode as written.
+func WithInterpolation(enabled bool) DecoderOption {
+	return func(d *Decoder) {
+		d.interpolate = enabled
+	}
+}
+
+// WithEnvLookup makes placeholders fall back to environment variables when
+// the referenced key is not defined. ${app.home} is looked up as app.home
+// and then as APP_HOME. It has no effect without WithInterpolation(true).
+func WithEnvLookup() DecoderOption {
+	return func(d *Decoder) {
+		d.envLookup = true
+	}
+}
+
+// WithTagDefaults makes Unmarshal and Decode apply the `default` tags of the
+// struct to the fields missing from the data, as Loader.Load does. As there, the
+// defaults of a struct behind a nil pointer only apply when the data sets a
+// key below it.
+func WithTagDefaults() DecoderOption {
+	return func(d *Decoder) {
+		d.defaults = true
+	}
+}
+
+// WithReset resets the struct to its zero value before its defaults are
+// applied, so that no field keeps a value from before decoding. Without it,
+// fields missing from the data and without defaults keep their values.
+func WithReset() DecoderOption {
+	return func(d *Decoder) {
+		d.reset = true
+	}
+}
+
+// WithOverlay decodes on top of the current contents of the struct. `default`
+// tags and SetDefaults are not applied to it and it is not reset, so fields
+// missing from the data keep their values. Structs allocated for nil pointers
+// still receive their defaults.
+func WithOverlay() DecoderOption {
+	return func(d *Decoder) {
+		d.overlay = true
+	}
+}
+
+// Defaulter is implemented by structs that set their own default values.
+// SetDefaults is called on the target struct and every nested struct before
+// the property values are applied, nested structs before their parents. The
+// values of `default` tags take precedence over those set by SetDefaults.
+// Embedded structs are not visited separately; their SetDefaults is promoted
+// to the outer struct as usual.
+type Defaulter interface {
+	SetDefaults()
+}
+
+// NewDecoder returns a Decoder configured with opts.
+func NewDecoder(opts ...DecoderOption) *Decoder {
+	d := &Decoder{lookups: defaultLookups()}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Unmarshal parses the properties data and stores the result in the struct
+// pointed to by v, using the default Decoder.
 func Unmarshal(data []byte, v interface{}) error {
+	return NewDecoder().Unmarshal(data, v)
+}
+
+// Unmarshal parses the properties data and stores the result in the struct
+// pointed to by v. SetDefaults is called first, see Defaulter; with
+// WithTagDefaults, fields missing from data take their `default` tag. The
+// decoded struct is then validated; see Validator.
+func (d *Decoder) Unmarshal(data []byte, v interface{}) error {
 	val := reflect.ValueOf(v)
 
 	// Ensure v is a pointer to a struct
@@ -14,11 +112,110 @@ func Unmarshal(data []byte, v interface{}) error {
 	}
 
 	// Parse the properties
-	props, err := parseProperties(data)
+	p, err := d.syntax.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	return d.decode(d.withTagDefaults(p, val.Elem().Type()), val.Elem())
+}
+
+// Decode stores the property set p in the struct pointed to by v, like
+// Unmarshal. p itself is left unchanged; aliases and sensitive keys are
+// applied to a copy.
+func (d *Decoder) Decode(p *Properties, v interface{}) error {
+	val := reflect.ValueOf(v)
+
+	// Ensure v is a pointer to a struct
+	if val.Kind() != reflect.Ptr || val.Elem().Kind() != reflect.Struct {
+		return errors.New("decode expects a pointer to a struct")
+	}
+
+	props := d.withTagDefaults(p, val.Elem().Type())
+	if props == p {
+		props = NewProperties()
+		props.Merge(p)
+	}
+	return d.decode(props, val.Elem())
+}
+
+// withTagDefaults layers p on top of the `default` tags of t in a new set, if
+// the Decoder applies them. Otherwise it returns p.
+func (d *Decoder) withTagDefaults(p *Properties, t reflect.Type) *Properties {
+	if !d.defaults || d.overlay {
+		return p
+	}
+	props := tagDefaults(t, p)
+	props.Merge(p)
+	return props
+}
+
+// decode sets the fields of structVal from a flat property set.
+func (d *Decoder) decode(p *Properties, structVal reflect.Value) error {
+	return d.decodeWithin(p, structVal, p, "")
+}
+
+// decodeWithin is decode for a property set whose keys are relative to prefix
+// in the set host, against which placeholders are resolved.
+func (d *Decoder) decodeWithin(p *Properties, structVal reflect.Value, host *Properties, prefix string) error {
+	// Move values from alternative keys to the keys of their fields
+	if err := d.applyAliases(p, structVal.Type()); err != nil {
+		return err
+	}
+
+	props, err := p.toMap()
 	if err != nil {
 		return err
 	}
 
+	if !d.overlay {
+		if d.reset {
+			structVal.Set(reflect.Zero(structVal.Type()))
+		}
+		setDefaults(structVal)
+	}
+
+	ds := &decodeState{props: p, decoder: d, decrypter: d.decrypter, decodeFuncs: d.decodeFuncs, hooks: d.hooks, fallback: d.fallback}
+	ds.emptyAbsent, ds.nulls = d.emptyAbsent, d.nulls
+	if d.interpolate {
+		ds.interp = newInterpolator(host, d.envLookup, d.lookups)
+		ds.interp.prefix = prefix
+	}
+
 	// Set the struct fields
-	return setStructFields(val.Elem(), props)
+	if err := ds.decodeStruct("", structVal, props); err != nil {
+		return err
+	}
+
+	// Check the `validate` tags and Validator implementations
+	return validate(structVal)
+}
+
+// setDefaults calls SetDefaults on the nested structs of structVal and then on
+// structVal itself. Nil pointers are left alone; they receive their defaults
+// when decoding allocates them. Nil embedded struct pointers are allocated
+// first, as decoding does, since SetDefaults may be promoted through them.
+func setDefaults(structVal reflect.Value) {
+	structType := structVal.Type()
+
+	for i := 0; i < structVal.NumField(); i++ {
+		field := structVal.Field(i)
+		fieldType := structType.Field(i)
+		if !field.CanSet() {
+			continue
+		}
+		if fieldType.Anonymous {
+			if field.Kind() == reflect.Ptr && field.Type().Elem().Kind() == reflect.Struct && field.IsNil() {
+				field.Set(reflect.New(field.Type().Elem()))
+			}
+			continue
+		}
+		if field.Kind() == reflect.Struct {
+			setDefaults(field)
+		}
+	}
+
+	if defaulter, ok := structVal.Addr().Interface().(Defaulter); ok {
+		defaulter.SetDefaults()
+	}
 }
@@ -89,7 +89,7 @@ app.port=8080
 
 func TestUnmarshalUnsupportedFieldType(t *testing.T) {
 	type UnsupportedConfig struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 
 	var config UnsupportedConfig
@@ -360,7 +360,7 @@ name=EmbeddedService
 
 func TestUnmarshalWithUnsupportedNestedStruct(t *testing.T) {
 	type InnerUnsupported struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 
 	type OuterConfig struct {
@@ -573,7 +573,7 @@ func TestUnmarshalWithCustomTextUnmarshaler(t *testing.T) {
 
 func TestUnmarshalWithUnsupportedNestedStructTypes(t *testing.T) {
 	type InnerUnsupported struct {
-		Data []string `property:"data"`
+		Data chan int `property:"data"`
 	}
 	type OuterConfig struct {
 		Name  string           `property:"name"`
@@ -753,3 +753,205 @@ name=FaultyService
 		t.Fatal("Expected Unmarshal to fail due to PropUnmarshaler error, but it did not")
 	}
 }
+
+func TestUnmarshalWithDefaultTags(t *testing.T) {
+	type Config struct {
+		Name    string  `property:"name" default:"DefaultService"`
+		Port    int     `property:"port" default:"8080"`
+		Timeout *int    `property:"timeout" default:"30"`
+		Ratio   float64 `property:"ratio"`
+	}
+
+	data := []byte(`
+name=ConfiguredService
+`)
+
+	var config Config
+	err := NewDecoder(WithTagDefaults()).Unmarshal(data, &config)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.Name != "ConfiguredService" {
+		t.Errorf("Expected Name 'ConfiguredService', got '%s'", config.Name)
+	}
+	if config.Port != 8080 {
+		t.Errorf("Expected Port 8080, got %d", config.Port)
+	}
+	if config.Timeout == nil || *config.Timeout != 30 {
+		t.Errorf("Expected Timeout 30, got %v", config.Timeout)
+	}
+
+	// Without the option the tags are ignored
+	var plain Config
+	if err := Unmarshal(data, &plain); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if plain.Port != 0 || plain.Timeout != nil {
+		t.Errorf("Expected no defaults, got %+v", plain)
+	}
+}
+
+func TestUnmarshalDefaultTagsBehindPointer(t *testing.T) {
+	type TLS struct {
+		Cert string `property:"cert"`
+		Port int    `property:"port" default:"443"`
+	}
+	type Config struct {
+		Name string `property:"name"`
+		TLS  *TLS   `property:"tls"`
+	}
+
+	dec := NewDecoder(WithTagDefaults())
+
+	var config Config
+	if err := dec.Unmarshal([]byte("name=app\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.TLS != nil {
+		t.Errorf("Expected TLS to stay nil, got %+v", config.TLS)
+	}
+
+	if err := dec.Unmarshal([]byte("tls.cert=app.pem\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.TLS == nil || config.TLS.Cert != "app.pem" || config.TLS.Port != 443 {
+		t.Errorf("Expected TLS {app.pem 443}, got %+v", config.TLS)
+	}
+}
+
+type DefaultedServer struct {
+	Host string `property:"host"`
+	Port int    `property:"port"`
+}
+
+func (s *DefaultedServer) SetDefaults() {
+	s.Host = "localhost"
+	s.Port = 8080
+}
+
+type DefaultedMetrics struct {
+	Enabled bool `property:"enabled"`
+	Port    int  `property:"port"`
+}
+
+func (m *DefaultedMetrics) SetDefaults() {
+	m.Enabled = true
+}
+
+type DefaultedConfig struct {
+	Server  DefaultedServer   `property:"server"`
+	Metrics DefaultedMetrics  `property:"metrics"`
+	Admin   *DefaultedServer  `property:"admin"`
+	Debug   *DefaultedMetrics `property:"debug"`
+}
+
+func (c *DefaultedConfig) SetDefaults() {
+	// Nested structs already hold their defaults
+	c.Metrics.Port = c.Server.Port + 1
+}
+
+func TestUnmarshalWithDefaulter(t *testing.T) {
+	data := []byte(`
+server.host=example.com
+admin.port=9000
+`)
+
+	config := DefaultedConfig{Server: DefaultedServer{Host: "stale"}}
+	err := Unmarshal(data, &config)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+
+	if config.Server.Host != "example.com" || config.Server.Port != 8080 {
+		t.Errorf("Expected Server {example.com 8080}, got %+v", config.Server)
+	}
+	if !config.Metrics.Enabled || config.Metrics.Port != 8081 {
+		t.Errorf("Expected Metrics {true 8081}, got %+v", config.Metrics)
+	}
+	if config.Admin == nil || config.Admin.Host != "localhost" || config.Admin.Port != 9000 {
+		t.Errorf("Expected Admin {localhost 9000}, got %+v", config.Admin)
+	}
+	if config.Debug != nil {
+		t.Errorf("Expected Debug to stay nil, got %+v", config.Debug)
+	}
+}
+
+func TestUnmarshalDefaulterThroughEmbeddedPointer(t *testing.T) {
+	type Outer struct {
+		*DefaultedServer
+		Y int `property:"y"`
+	}
+
+	var config Outer
+	if err := Unmarshal([]byte("y=2\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.DefaultedServer == nil || config.Host != "localhost" || config.Port != 8080 || config.Y != 2 {
+		t.Errorf("Expected {localhost 8080} and Y 2, got %+v and Y %d", config.DefaultedServer, config.Y)
+	}
+}
+
+func TestUnmarshalWithOverlay(t *testing.T) {
+	type Config struct {
+		Name  string `property:"name" default:"DefaultService"`
+		Port  int    `property:"port" default:"8080"`
+		Debug bool   `property:"debug"`
+	}
+
+	config := Config{Name: "Existing", Port: 9000}
+	err := NewDecoder(WithOverlay()).Unmarshal([]byte("debug=true\n"), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != "Existing" || config.Port != 9000 || !config.Debug {
+		t.Errorf("Expected {Existing 9000 true}, got %+v", config)
+	}
+
+	// Without the option the defaults apply
+	err = NewDecoder(WithTagDefaults()).Unmarshal([]byte("debug=true\n"), &config)
+	if err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != "DefaultService" || config.Port != 8080 {
+		t.Errorf("Expected {DefaultService 8080 true}, got %+v", config)
+	}
+
+	// Decode applies them the same way
+	p, err := Parse([]byte("name=Decoded\n"))
+	if err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	config = Config{Port: 1}
+	if err := NewDecoder(WithTagDefaults()).Decode(p, &config); err != nil {
+		t.Fatalf("Decode failed: %v", err)
+	}
+	if config.Name != "Decoded" || config.Port != 8080 {
+		t.Errorf("Expected {Decoded 8080}, got %+v", config)
+	}
+	if _, ok := p.Get("port"); ok {
+		t.Error("Expected Decode to leave the property set without defaults")
+	}
+}
+
+func TestUnmarshalKeepsMissingFields(t *testing.T) {
+	type Config struct {
+		Name string `property:"name"`
+		Dir  string `property:"dir"`
+	}
+
+	config := Config{Name: "keep"}
+	if err := Unmarshal([]byte("dir=/x\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != "keep" || config.Dir != "/x" {
+		t.Errorf("Expected {keep /x}, got %+v", config)
+	}
+
+	if err := NewDecoder(WithReset()).Unmarshal([]byte("dir=/y\n"), &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Name != "" || config.Dir != "/y" {
+		t.Errorf("Expected { /y} after a reset, got %+v", config)
+	}
+}
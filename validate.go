@@ -0,0 +1,341 @@
+package dotprops
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// Validator is implemented by structs that check their own values after
+// decoding. Validate is called on every nested struct before its parent.
+// Embedded structs are not visited separately; their Validate is promoted to
+// the outer struct.
+type Validator interface {
+	Validate() error
+}
+
+// FieldError is a single failed validation.
+type FieldError struct {
+	// Key is the full property key of the field, or the prefix of the struct
+	// whose Validate method failed. It is empty for the top-level struct.
+	Key string
+	Err error
+}
+
+// Error formats the error as "key: message".
+func (e *FieldError) Error() string {
+	if e.Key == "" {
+		return e.Err.Error()
+	}
+	return e.Key + ": " + e.Err.Error()
+}
+
+// Unwrap returns the underlying error.
+func (e *FieldError) Unwrap() error {
+	return e.Err
+}
+
+// ValidationError reports every failed validation of a decode pass.
+type ValidationError struct {
+	Errors []*FieldError
+}
+
+// Error lists every failure.
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Errors))
+	for i, err := range e.Errors {
+		msgs[i] = err.Error()
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// validator collects the failures of a validation pass.
+type validator struct {
+	errs []*FieldError
+	// visited guards against pointer cycles in pre-populated structs.
+	visited map[uintptr]bool
+}
+
+// validate checks the `validate` tags of every field of structVal and calls
+// Validate on every nested struct, bottom-up. It returns a *ValidationError
+// if any check fails.
+func validate(structVal reflect.Value) error {
+	v := &validator{visited: make(map[uintptr]bool)}
+	if err := v.validateStruct("", structVal); err != nil {
+		return err
+	}
+	if len(v.errs) > 0 {
+		return &ValidationError{Errors: v.errs}
+	}
+	return nil
+}
+
+// validateStruct validates the fields of a struct and then the struct itself.
+// The returned error reports a malformed `validate` tag.
+func (v *validator) validateStruct(prefix string, structVal reflect.Value) error {
+	if err := v.validateFields(prefix, structVal); err != nil {
+		return err
+	}
+
+	// Validate the struct itself after its fields
+	var validatable interface{} = structVal.Interface()
+	if structVal.CanAddr() {
+		validatable = structVal.Addr().Interface()
+	}
+	if validator, ok := validatable.(Validator); ok {
+		if err := validator.Validate(); err != nil {
+			v.errs = append(v.errs, &FieldError{Key: prefix, Err: err})
+		}
+	}
+
+	return nil
+}
+
+// validateFields validates the fields of a struct. The fields of embedded
+// structs are validated with them, but Validate is not called on embedded
+// structs separately; it is promoted to the outer struct.
+func (v *validator) validateFields(prefix string, structVal reflect.Value) error {
+	structType := structVal.Type()
+
+	for i := 0; i < structVal.NumField(); i++ {
+		field := structVal.Field(i)
+		fieldType := structType.Field(i)
+
+		// Skip unexported fields
+		if !fieldType.IsExported() {
+			continue
+		}
+
+		fullKey := prefix
+		if !fieldType.Anonymous {
+			fullKey = joinKey(prefix, propertyKey(fieldType))
+		}
+
+		if tag, ok := fieldType.Tag.Lookup("validate"); ok {
+			if err := v.validateField(fullKey, field, tag); err != nil {
+				return fmt.Errorf("invalid validate tag on field '%s': %v", fullKey, err)
+			}
+		}
+
+		if fieldType.Anonymous {
+			embedded := field
+			if embedded.Kind() == reflect.Ptr && !embedded.IsNil() {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if err := v.validateFields(fullKey, embedded); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		if err := v.validateNested(fullKey, field); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// validateNested validates the structs held by val, the value of fullKey,
+// directly or through interfaces, pointers, map values and slice elements.
+// Map values are keyed below fullKey as when decoding, slice elements by
+// their index.
+func (v *validator) validateNested(fullKey string, val reflect.Value) error {
+	if val.Kind() == reflect.Interface {
+		if val.IsNil() {
+			return nil
+		}
+		val = val.Elem()
+	}
+	if val.Kind() == reflect.Ptr {
+		if val.IsNil() || v.visited[val.Pointer()] {
+			return nil
+		}
+		v.visited[val.Pointer()] = true
+		val = val.Elem()
+	}
+
+	switch val.Kind() {
+	case reflect.Struct:
+		// Map values are not addressable; validate a copy so that
+		// Validate methods on pointers are found
+		if !val.CanAddr() {
+			addressable := reflect.New(val.Type()).Elem()
+			addressable.Set(val)
+			val = addressable
+		}
+		return v.validateStruct(fullKey, val)
+	case reflect.Map:
+		if !holdsStructs(val.Type().Elem()) {
+			return nil
+		}
+		keys := val.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i]) < fmt.Sprint(keys[j])
+		})
+		for _, k := range keys {
+			if err := v.validateNested(joinKey(fullKey, fmt.Sprint(k)), val.MapIndex(k)); err != nil {
+				return err
+			}
+		}
+	case reflect.Slice, reflect.Array:
+		if !holdsStructs(val.Type().Elem()) {
+			return nil
+		}
+		for i := 0; i < val.Len(); i++ {
+			if err := v.validateNested(fmt.Sprintf("%s[%d]", fullKey, i), val.Index(i)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// holdsStructs reports whether values of t may hold structs to validate.
+func holdsStructs(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Struct, reflect.Ptr, reflect.Interface, reflect.Map, reflect.Slice, reflect.Array:
+		return true
+	}
+	return false
+}
+
+// validateField applies the comma-separated rules of a `validate` tag to
+// field. A pattern rule extends to the end of the tag so that its expression
+// may contain commas.
+func (v *validator) validateField(fullKey string, field reflect.Value, tag string) error {
+	for tag != "" {
+		var rule string
+		if strings.HasPrefix(tag, "pattern=") {
+			rule, tag = tag, ""
+		} else {
+			rule, tag, _ = strings.Cut(tag, ",")
+		}
+
+		name, arg, _ := strings.Cut(strings.TrimSpace(rule), "=")
+		if name == "" {
+			continue
+		}
+
+		failure, err := checkRule(name, arg, field)
+		if err != nil {
+			return err
+		}
+		if failure != "" {
+			v.errs = append(v.errs, &FieldError{Key: fullKey, Err: fmt.Errorf("%s", failure)})
+		}
+	}
+	return nil
+}
+
+// checkRule applies a single rule to field and returns a failure message, or
+// an error if the rule is malformed.
+func checkRule(name, arg string, field reflect.Value) (string, error) {
+	if name == "nonempty" {
+		if field.IsZero() {
+			return "must not be empty", nil
+		}
+		return "", nil
+	}
+
+	// Other rules do not apply to unset optional fields
+	if field.Kind() == reflect.Ptr {
+		if field.IsNil() {
+			return "", nil
+		}
+		field = field.Elem()
+	}
+
+	switch name {
+	case "min", "max":
+		limit, err := strconv.ParseFloat(arg, 64)
+		if err != nil {
+			return "", fmt.Errorf("invalid %s value '%s'", name, arg)
+		}
+		n, isLength, err := measure(field)
+		if err != nil {
+			return "", fmt.Errorf("%s: %v", name, err)
+		}
+		if name == "min" && n < limit {
+			if isLength {
+				return fmt.Sprintf("length must be at least %s", arg), nil
+			}
+			return fmt.Sprintf("must be at least %s", arg), nil
+		}
+		if name == "max" && n > limit {
+			if isLength {
+				return fmt.Sprintf("length must be at most %s", arg), nil
+			}
+			return fmt.Sprintf("must be at most %s", arg), nil
+		}
+	case "oneof":
+		options := strings.Fields(arg)
+		value := fmt.Sprint(field.Interface())
+		for _, option := range options {
+			if value == option {
+				return "", nil
+			}
+		}
+		return fmt.Sprintf("must be one of %s", strings.Join(options, ", ")), nil
+	case "pattern":
+		re, err := regexp.Compile(arg)
+		if err != nil {
+			return "", fmt.Errorf("invalid pattern: %v", err)
+		}
+		if !re.MatchString(fmt.Sprint(field.Interface())) {
+			return fmt.Sprintf("must match %s", arg), nil
+		}
+	case "port":
+		if !validPort(fmt.Sprint(field.Interface()), false) {
+			return "must be a port number between 1 and 65535", nil
+		}
+	case "url":
+		u, err := url.Parse(fmt.Sprint(field.Interface()))
+		if err != nil || u.Scheme == "" || u.Host == "" {
+			return "must be an absolute URL", nil
+		}
+	case "hostport":
+		_, port, err := net.SplitHostPort(fmt.Sprint(field.Interface()))
+		if err != nil || !validPort(port, true) {
+			return "must be in host:port form", nil
+		}
+	default:
+		return "", fmt.Errorf("unknown rule '%s'", name)
+	}
+	return "", nil
+}
+
+// measure returns the numeric value of a number field or the length of a
+// string field.
+func measure(field reflect.Value) (float64, bool, error) {
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(field.Int()), false, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(field.Uint()), false, nil
+	case reflect.Float32, reflect.Float64:
+		return field.Float(), false, nil
+	case reflect.String:
+		return float64(utf8.RuneCountInString(field.String())), true, nil
+	default:
+		return 0, false, fmt.Errorf("unsupported field type: %s", field.Kind())
+	}
+}
+
+// validPort reports whether s is a port number. Port 0 is only accepted if
+// allowZero is set.
+func validPort(s string, allowZero bool) bool {
+	port, err := strconv.ParseUint(s, 10, 16)
+	if err != nil {
+		return false
+	}
+	return port > 0 || allowZero
+}
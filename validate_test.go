@@ -0,0 +1,220 @@
+package dotprops
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+type ValidatedConfig struct {
+	Name    string         `property:"app.name" validate:"nonempty,max=8"`
+	Port    int            `property:"app.port" validate:"port"`
+	Workers *int           `property:"app.workers" validate:"min=1,max=64"`
+	Mode    string         `property:"app.mode" validate:"oneof=dev prod"`
+	Version string         `property:"app.version" validate:"pattern=^[0-9]+(\\.[0-9]+){1,2}$"`
+	Ratio   float64        `property:"app.ratio" validate:"min=0,max=1"`
+	Server  ServerSettings `property:"server"`
+}
+
+type ServerSettings struct {
+	URL     string `property:"url" validate:"url"`
+	Listen  string `property:"listen" validate:"hostport"`
+	MinConn int    `property:"min.conn"`
+	MaxConn int    `property:"max.conn"`
+}
+
+func (s *ServerSettings) Validate() error {
+	if s.MinConn > s.MaxConn {
+		return errors.New("min.conn must not exceed max.conn")
+	}
+	return nil
+}
+
+var validatedOrder []string
+
+type OrderChild struct{}
+
+func (OrderChild) Validate() error {
+	validatedOrder = append(validatedOrder, "child")
+	return nil
+}
+
+type OrderParent struct {
+	Child OrderChild `property:"child"`
+}
+
+func (OrderParent) Validate() error {
+	validatedOrder = append(validatedOrder, "parent")
+	return errors.New("parent failed")
+}
+
+func TestValidationPasses(t *testing.T) {
+	data := []byte(`
+app.name=demo
+app.port=8080
+app.workers=4
+app.mode=prod
+app.version=1.2.3
+app.ratio=0.5
+server.url=https://example.com/api
+server.listen=:8080
+server.min.conn=1
+server.max.conn=10
+`)
+
+	var config ValidatedConfig
+	if err := Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+}
+
+func TestValidationReportsAllFailures(t *testing.T) {
+	data := []byte(`
+app.port=70000
+app.workers=0
+app.mode=test
+app.version=v1
+app.ratio=1.5
+server.url=example.com
+server.listen=localhost
+server.min.conn=10
+server.max.conn=1
+`)
+
+	var config ValidatedConfig
+	err := Unmarshal(data, &config)
+
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+
+	expected := map[string]string{
+		"app.name":      "must not be empty",
+		"app.port":      "must be a port number between 1 and 65535",
+		"app.workers":   "must be at least 1",
+		"app.mode":      "must be one of dev, prod",
+		"app.version":   "must match",
+		"app.ratio":     "must be at most 1",
+		"server.url":    "must be an absolute URL",
+		"server.listen": "must be in host:port form",
+		"server":        "min.conn must not exceed max.conn",
+	}
+	if len(verr.Errors) != len(expected) {
+		t.Errorf("Expected %d failures, got %d: %v", len(expected), len(verr.Errors), err)
+	}
+	for _, fe := range verr.Errors {
+		msg, ok := expected[fe.Key]
+		if !ok {
+			t.Errorf("Unexpected failure for key '%s': %v", fe.Key, fe.Err)
+			continue
+		}
+		if !strings.HasPrefix(fe.Err.Error(), msg) {
+			t.Errorf("Expected failure for '%s' to start with %q, got %q", fe.Key, msg, fe.Err)
+		}
+	}
+}
+
+func TestValidationSkipsNilPointers(t *testing.T) {
+	data := []byte("app.name=demo\napp.port=80\napp.mode=dev\napp.version=1.0\nserver.url=http://x\nserver.listen=x:1\n")
+
+	var config ValidatedConfig
+	if err := Unmarshal(data, &config); err != nil {
+		t.Fatalf("Unmarshal failed: %v", err)
+	}
+	if config.Workers != nil {
+		t.Errorf("Expected Workers to be nil, got %d", *config.Workers)
+	}
+}
+
+func TestValidationStringLength(t *testing.T) {
+	data := []byte("app.name=much-too-long\napp.port=80\napp.mode=dev\napp.version=1.0\nserver.url=http://x\nserver.listen=x:1\n")
+
+	var config ValidatedConfig
+	err := Unmarshal(data, &config)
+	if err == nil || !strings.Contains(err.Error(), "app.name: length must be at most 8") {
+		t.Errorf("Expected a length failure for app.name, got: %v", err)
+	}
+}
+
+func TestValidatorCalledBottomUp(t *testing.T) {
+	validatedOrder = nil
+
+	var config OrderParent
+	err := Unmarshal([]byte(""), &config)
+	if err == nil || err.Error() != "validation failed: parent failed" {
+		t.Errorf("Expected the parent failure, got: %v", err)
+	}
+	if strings.Join(validatedOrder, ",") != "child,parent" {
+		t.Errorf("Expected child to be validated before parent, got %v", validatedOrder)
+	}
+}
+
+type EmbeddedInner struct {
+	Level int `property:"level" validate:"min=1"`
+}
+
+func (EmbeddedInner) Validate() error {
+	return errors.New("inner bad")
+}
+
+func TestValidatorOnEmbeddedStructCalledOnce(t *testing.T) {
+	var config struct {
+		EmbeddedInner
+		Name string `property:"name"`
+	}
+	err := Unmarshal([]byte("level=0\n"), &config)
+	if err == nil || err.Error() != "validation failed: level: must be at least 1; inner bad" {
+		t.Errorf("Expected the tag failure and one Validate failure, got: %v", err)
+	}
+}
+
+func TestValidationInvalidTag(t *testing.T) {
+	var config struct {
+		Port int `property:"port" validate:"between=1"`
+	}
+	err := Unmarshal([]byte("port=1\n"), &config)
+	if err == nil || !strings.Contains(err.Error(), "unknown rule 'between'") {
+		t.Errorf("Expected an unknown rule error, got: %v", err)
+	}
+
+	var ve *ValidationError
+	if errors.As(err, &ve) {
+		t.Errorf("Expected a malformed tag not to be reported as a validation failure")
+	}
+}
+
+func TestValidationDescendsIntoMapsAndSlices(t *testing.T) {
+	type Config struct {
+		Servers map[string]ServerSettings  `property:"servers"`
+		Pointed map[string]*ServerSettings `property:"pointed"`
+		List    []ServerSettings           `property:"list"`
+	}
+
+	data := []byte(`
+servers.a.url=https://example.com
+servers.a.listen=:80
+servers.b.url=not a url
+servers.b.listen=:80
+pointed.c.url=https://example.com
+pointed.c.listen=:80
+pointed.c.min.conn=2
+list=[{"URL":"https://example.com","Listen":"nowhere"}]
+`)
+
+	var config Config
+	err := NewDecoder(WithDecodeFallback(JSONFallback)).Unmarshal(data, &config)
+	var verr *ValidationError
+	if !errors.As(err, &verr) {
+		t.Fatalf("Expected a *ValidationError, got %v", err)
+	}
+
+	var keys []string
+	for _, fe := range verr.Errors {
+		keys = append(keys, fe.Key)
+	}
+	expected := "servers.b.url,pointed.c,list[0].listen"
+	if strings.Join(keys, ",") != expected {
+		t.Errorf("Expected failures for %s, got %v", expected, verr)
+	}
+}